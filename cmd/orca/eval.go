@@ -0,0 +1,175 @@
+package main
+
+import (
+	"Orca/pkg/scanning"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+)
+
+func evalCommand(getScanner func() (*scanning.Scanner, error)) *cli.Command {
+	var corpus string
+	var output string
+	var baseline string
+	var showFindings bool
+
+	return &cli.Command{
+		Name:  "eval",
+		Usage: "Measure the precision and recall of the patterns against a labelled corpus",
+		Description: "Scans every file in the corpus directory and compares the matches with the findings expected in " +
+			"each file's labels file, named after the file with a " + scanning.CorpusLabelsSuffix + " suffix. A " +
+			"labels file is a JSON list of {\"line\": 3, \"kind\": \"AWS Access Key\"}, and files without one are " +
+			"expected to have no findings.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "corpus",
+				Usage:       "The directory of labelled files to scan.",
+				Required:    true,
+				Destination: &corpus,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Usage:       "A file to save the results to as JSON, so that a later run can be compared with them.",
+				Destination: &output,
+			},
+			&cli.StringFlag{
+				Name:        "baseline",
+				Usage:       "The saved results of a previous run to compare with, e.g. from before a change to the patterns.",
+				Destination: &baseline,
+			},
+			&cli.BoolFlag{
+				Name:        "findings",
+				Usage:       "List each false positive and false negative.",
+				Destination: &showFindings,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if info, err := os.Stat(corpus); err != nil || !info.IsDir() {
+				return fmt.Errorf("the corpus \"%s\" must be a directory", corpus)
+			}
+
+			var previous *scanning.Evaluation
+			if len(baseline) > 0 {
+				data, err := ioutil.ReadFile(baseline)
+				if err != nil {
+					return err
+				}
+				if previous, err = scanning.ParseEvaluation(data); err != nil {
+					return err
+				}
+			}
+
+			scanner, err := getScanner()
+			if err != nil {
+				return err
+			}
+
+			evaluation, err := scanner.EvaluateCorpus(corpus)
+			if err != nil {
+				return err
+			}
+
+			if evaluation.Files == 0 {
+				return errors.New("the corpus has no files to scan")
+			}
+
+			printEvaluation(os.Stdout, evaluation, showFindings)
+			if previous != nil {
+				printEvaluationChanges(os.Stdout, previous, evaluation)
+			}
+
+			if len(output) > 0 {
+				data, err := json.MarshalIndent(evaluation, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(output, data, 0644); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Results written to %s\n", output)
+			}
+
+			return nil
+		},
+	}
+}
+
+func printEvaluation(output io.Writer, evaluation *scanning.Evaluation, showFindings bool) {
+	fmt.Fprintf(output, "Scanned %d files (%d bytes) in %.2fs, %.0f bytes/s\n",
+		evaluation.Files, evaluation.Bytes, evaluation.DurationSeconds, evaluation.BytesPerSecond())
+	if evaluation.IncompleteFiles > 0 {
+		fmt.Fprintf(output, "%d files could not be scanned in full\n", evaluation.IncompleteFiles)
+	}
+	fmt.Fprintln(output)
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KIND\tTP\tFP\tFN\tPRECISION\tRECALL")
+	for _, kind := range append(evaluation.Kinds, evaluation.Totals) {
+		name := kind.Kind
+		if name == "" {
+			name = "Total"
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%.3f\t%.3f\n",
+			name, kind.TruePositives, kind.FalsePositives, kind.FalseNegatives, kind.Precision(), kind.Recall())
+	}
+	writer.Flush()
+
+	if showFindings && len(evaluation.Findings) > 0 {
+		fmt.Fprintln(output)
+		for _, finding := range evaluation.Findings {
+			fmt.Fprintf(output, "%s:%d: %s (%s)\n", finding.Path, finding.Line, finding.Kind, finding.Outcome)
+		}
+	}
+}
+
+func printEvaluationChanges(output io.Writer, previous *scanning.Evaluation, current *scanning.Evaluation) {
+	fmt.Fprintf(output, "\nThroughput: %.0f bytes/s, previously %.0f bytes/s\n",
+		current.BytesPerSecond(), previous.BytesPerSecond())
+
+	changes := scanning.CompareEvaluations(previous, current)
+	if len(changes) == 0 {
+		fmt.Fprintln(output, "No changes to the findings since the baseline")
+		return
+	}
+
+	fmt.Fprintln(output, "Changes since the baseline:")
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KIND\tTP\tFP\tFN\tPRECISION\tRECALL")
+	totals := scanning.EvaluationChange{Previous: previous.Totals, Current: current.Totals}
+	for _, change := range append(changes, totals) {
+		name := change.Kind
+		if name == "" {
+			name = "Total"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			countChange(change.Previous.TruePositives, change.Current.TruePositives),
+			countChange(change.Previous.FalsePositives, change.Current.FalsePositives),
+			countChange(change.Previous.FalseNegatives, change.Current.FalseNegatives),
+			ratioChange(change.Previous.Precision(), change.Current.Precision()),
+			ratioChange(change.Previous.Recall(), change.Current.Recall()))
+	}
+	writer.Flush()
+}
+
+func countChange(previous int, current int) string {
+	if previous == current {
+		return fmt.Sprint(current)
+	}
+
+	return fmt.Sprintf("%d (%+d)", current, current-previous)
+}
+
+func ratioChange(previous float64, current float64) string {
+	if fmt.Sprintf("%.3f", previous) == fmt.Sprintf("%.3f", current) {
+		return fmt.Sprintf("%.3f", current)
+	}
+
+	return fmt.Sprintf("%.3f (%+.3f)", current, current-previous)
+}
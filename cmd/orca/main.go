@@ -4,14 +4,19 @@ import (
 	"Orca/pkg/crypto"
 	"Orca/pkg/handlers"
 	"Orca/pkg/scanning"
+	"context"
 	"crypto/rsa"
 	"errors"
 	"fmt"
 	"github.com/urfave/cli/v2"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -23,6 +28,100 @@ func main() {
 	var secret string
 	var appId int
 	var patternsLocation string
+	var patternsSignatureLocation string
+	var patternsPublicKeys cli.StringSlice
+	var scanBudget = scanning.DefaultScanBudget()
+	var diffMode bool
+	var scanAllFiles bool
+	var disabledDetectors cli.StringSlice
+	var previewOptions = scanning.DefaultPreviewOptions()
+	var honeytokensLocation string
+	var detectorPlugins cli.StringSlice
+	var pluginTimeout time.Duration
+	var eventTimeout time.Duration
+	var scanWorkers int
+	var securityAlerts handlers.SecurityAlertOptions
+	var triageFile string
+	var disableTriageModel bool
+
+	getPatternStore := func() (scanning.PatternStore, error) {
+		if len(patternsPublicKeys.Value()) == 0 {
+			return scanning.NewPatternStore(patternsLocation)
+		}
+
+		publicKeys, err := decodePublicKeys(patternsPublicKeys.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		return scanning.NewSignedPatternStore(patternsLocation, patternsSignatureLocation, publicKeys)
+	}
+
+	getScannerOptions := func() (scanning.ScannerOptions, error) {
+		if scanBudget.MaxLineLength > 0 && scanBudget.WindowOverlap >= scanBudget.MaxLineLength {
+			return scanning.ScannerOptions{}, errors.New("the line window overlap must be smaller than the maximum line length")
+		}
+
+		if scanWorkers < 1 {
+			return scanning.ScannerOptions{}, errors.New("at least one scan worker is needed")
+		}
+
+		detectorOptions := scanning.DetectorOptions{DisabledDetectors: disabledDetectors.Value()}
+		if err := detectorOptions.Validate(); err != nil {
+			return scanning.ScannerOptions{}, err
+		}
+
+		var plugins []scanning.DetectorPlugin
+		for _, value := range detectorPlugins.Value() {
+			plugin, err := scanning.ParseDetectorPlugin(value)
+			if err != nil {
+				return scanning.ScannerOptions{}, err
+			}
+			plugin.Timeout = pluginTimeout
+			plugins = append(plugins, plugin)
+		}
+
+		var honeytokenStore scanning.HoneytokenStore
+		if len(honeytokensLocation) > 0 {
+			var err error
+			if honeytokenStore, err = scanning.NewHoneytokenStore(honeytokensLocation); err != nil {
+				return scanning.ScannerOptions{}, err
+			}
+		}
+
+		// The model is turned off by not training it, while decisions can still be recorded
+		scannerTriageFile := triageFile
+		if disableTriageModel {
+			scannerTriageFile = ""
+		}
+
+		return scanning.ScannerOptions{
+			Detectors:    detectorOptions,
+			Budget:       scanBudget,
+			DiffMode:     diffMode,
+			Preview:      previewOptions,
+			ScanAllFiles: scanAllFiles,
+			Honeytokens:  honeytokenStore,
+			Plugins:      plugins,
+			TriageFile:   scannerTriageFile,
+			Workers:      scanWorkers,
+		}, nil
+	}
+
+	// getScanner creates a scanner for the commands which scan local files
+	getScanner := func() (*scanning.Scanner, error) {
+		scannerOptions, err := getScannerOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		patternStore, err := getPatternStore()
+		if err != nil {
+			return nil, err
+		}
+
+		return scanning.NewScanner(&patternStore, scannerOptions)
+	}
 
 	app := &cli.App{
 		Name:  "Orca",
@@ -75,9 +174,193 @@ func main() {
 				Name:        "patterns-location",
 				Aliases:     []string{"pl"},
 				EnvVars:     []string{"ORCA_PATTERNS_LOCATION"},
-				Usage:       "The location of the patterns to check for. Accepts a file path or HTTP URL.",
+				Usage:       "The location of the patterns to check for. Accepts a file path or HTTP URL, optionally prefixed with the format of the rules (gitleaks:, git-secrets: or detect-secrets:).",
 				Destination: &patternsLocation,
 			},
+			&cli.StringSliceFlag{
+				Name:        "patterns-public-key",
+				EnvVars:     []string{"ORCA_PATTERNS_PUBLIC_KEY"},
+				Usage:       "An Ed25519 public key trusted to sign patterns, as a PEM file path or base64 encoded key. When given, patterns are only loaded if their signature verifies.",
+				Destination: &patternsPublicKeys,
+			},
+			&cli.StringFlag{
+				Name:        "patterns-signature-location",
+				EnvVars:     []string{"ORCA_PATTERNS_SIGNATURE_LOCATION"},
+				Usage:       "The location of the patterns' detached signature. Defaults to the patterns location with a .sig extension.",
+				Destination: &patternsSignatureLocation,
+			},
+			&cli.DurationFlag{
+				Name:        "max-file-scan-time",
+				EnvVars:     []string{"ORCA_MAX_FILE_SCAN_TIME"},
+				Value:       scanBudget.MaxFileDuration,
+				Usage:       "The longest time to spend scanning a single file before reporting it as incomplete. 0 disables the limit.",
+				Destination: &scanBudget.MaxFileDuration,
+			},
+			&cli.DurationFlag{
+				Name:        "max-pattern-scan-time",
+				EnvVars:     []string{"ORCA_MAX_PATTERN_SCAN_TIME"},
+				Value:       scanBudget.MaxPatternDuration,
+				Usage:       "The longest time a single pattern may spend scanning a file before it is skipped. 0 disables the limit.",
+				Destination: &scanBudget.MaxPatternDuration,
+			},
+			&cli.IntFlag{
+				Name:        "max-file-scan-bytes",
+				EnvVars:     []string{"ORCA_MAX_FILE_SCAN_BYTES"},
+				Value:       scanBudget.MaxFileBytes,
+				Usage:       "The most bytes to scan in a single file before reporting it as incomplete. 0 disables the limit.",
+				Destination: &scanBudget.MaxFileBytes,
+			},
+			&cli.IntFlag{
+				Name:        "max-pattern-scan-bytes",
+				EnvVars:     []string{"ORCA_MAX_PATTERN_SCAN_BYTES"},
+				Value:       scanBudget.MaxPatternBytes,
+				Usage:       "The most bytes a single pattern may scan in a file before it is skipped. 0 disables the limit.",
+				Destination: &scanBudget.MaxPatternBytes,
+			},
+			&cli.IntFlag{
+				Name:        "max-line-length",
+				EnvVars:     []string{"ORCA_MAX_LINE_LENGTH"},
+				Value:       scanBudget.MaxLineLength,
+				Usage:       "Lines longer than this many bytes are scanned in overlapping windows. 0 disables windowing.",
+				Destination: &scanBudget.MaxLineLength,
+			},
+			&cli.IntFlag{
+				Name:        "line-window-overlap",
+				EnvVars:     []string{"ORCA_LINE_WINDOW_OVERLAP"},
+				Value:       scanBudget.WindowOverlap,
+				Usage:       "How many bytes each window of a long line overlaps the previous window by.",
+				Destination: &scanBudget.WindowOverlap,
+			},
+			&cli.BoolFlag{
+				Name:        "diff-mode",
+				EnvVars:     []string{"ORCA_DIFF_MODE"},
+				Usage:       "Only scan the lines added by each commit in a pull request, falling back to scanning the whole file when the patch is truncated.",
+				Destination: &diffMode,
+			},
+			&cli.BoolFlag{
+				Name:        "scan-all-files",
+				EnvVars:     []string{"ORCA_SCAN_ALL_FILES"},
+				Usage:       "Scan vendored, generated and minified files, which are otherwise skipped.",
+				Destination: &scanAllFiles,
+			},
+			&cli.IntFlag{
+				Name:        "scan-workers",
+				EnvVars:     []string{"ORCA_SCAN_WORKERS"},
+				Value:       4,
+				Usage:       "How many files from a pull request or push to fetch and scan at once.",
+				Destination: &scanWorkers,
+			},
+			&cli.StringSliceFlag{
+				Name:    "disable-detector",
+				EnvVars: []string{"ORCA_DISABLE_DETECTORS"},
+				Usage: fmt.Sprintf("Turn off a built-in detector. Can be given more than once. Detectors: %s.",
+					strings.Join(scanning.BuiltInDetectorNames(), ", ")),
+				Destination: &disabledDetectors,
+			},
+			&cli.StringSliceFlag{
+				Name:        "detector-plugin",
+				EnvVars:     []string{"ORCA_DETECTOR_PLUGINS"},
+				Usage:       "An external detector to run on each file, as name=command. The command is sent the file as JSON on stdin and writes the matches it finds as JSON to stdout. Can be given more than once.",
+				Destination: &detectorPlugins,
+			},
+			&cli.DurationFlag{
+				Name:        "detector-plugin-timeout",
+				EnvVars:     []string{"ORCA_DETECTOR_PLUGIN_TIMEOUT"},
+				Value:       10 * time.Second,
+				Usage:       "The longest time a detector plugin may take for a single file before the file's scan is reported as incomplete.",
+				Destination: &pluginTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "event-timeout",
+				EnvVars:     []string{"ORCA_EVENT_TIMEOUT"},
+				Value:       5 * time.Minute,
+				Usage:       "The longest time to spend handling a single webhook event. Files which weren't scanned in time are reported as incomplete. 0 disables the limit.",
+				Destination: &eventTimeout,
+			},
+			&cli.StringFlag{
+				Name:        "honeytokens-location",
+				EnvVars:     []string{"ORCA_HONEYTOKENS_LOCATION"},
+				Usage:       "The location of the honeytoken registry, a JSON file of canary credentials' names and SHA-256 fingerprints. Honeytokens are always reported and can't be suppressed by repositories.",
+				Destination: &honeytokensLocation,
+			},
+			&cli.StringFlag{
+				Name:        "security-alert-repository",
+				EnvVars:     []string{"ORCA_SECURITY_ALERT_REPOSITORY"},
+				Usage:       "The repository (owner/name) to open an issue in when a honeytoken is found. The app must be installed on it. Without one, honeytoken alerts are only logged.",
+				Destination: &securityAlerts.Repository,
+			},
+			&cli.StringFlag{
+				Name:        "security-team",
+				EnvVars:     []string{"ORCA_SECURITY_TEAM"},
+				Usage:       "The team (organisation/team) to mention in honeytoken alerts.",
+				Destination: &securityAlerts.Team,
+			},
+			&cli.StringFlag{
+				Name:        "triage-file",
+				EnvVars:     []string{"ORCA_TRIAGE_FILE"},
+				Usage:       "A JSON file of triage decisions on past matches, recorded with the triage command. A model trained on them scores how likely each new match is to be a real secret.",
+				Destination: &triageFile,
+			},
+			&cli.BoolFlag{
+				Name:        "disable-triage-model",
+				EnvVars:     []string{"ORCA_DISABLE_TRIAGE_MODEL"},
+				Usage:       "Don't score matches with the triage model.",
+				Destination: &disableTriageModel,
+			},
+			&cli.IntFlag{
+				Name:        "preview-prefix",
+				EnvVars:     []string{"ORCA_PREVIEW_PREFIX"},
+				Value:       previewOptions.VisiblePrefix,
+				Usage:       "How many characters from the start of a matched value to show in reports. At most a quarter of the value is shown.",
+				Destination: &previewOptions.VisiblePrefix,
+			},
+			&cli.IntFlag{
+				Name:        "preview-suffix",
+				EnvVars:     []string{"ORCA_PREVIEW_SUFFIX"},
+				Value:       previewOptions.VisibleSuffix,
+				Usage:       "How many characters from the end of a matched value to show in reports. At most a quarter of the value is shown.",
+				Destination: &previewOptions.VisibleSuffix,
+			},
+		},
+		Commands: []*cli.Command{
+			patternsCommand(),
+			evalCommand(getScanner),
+			triageCommand(getScanner, &triageFile),
+			{
+				Name:      "scan",
+				Usage:     "Scan local files, or stdin as -, for potential credentials",
+				ArgsUsage: "<file>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() < 1 {
+						return errors.New("at least one file to scan must be provided")
+					}
+
+					scanner, err := getScanner()
+					if err != nil {
+						return err
+					}
+
+					// Use the settings of the repository being scanned, if it has any
+					if data, err := ioutil.ReadFile(scanning.RepositoryConfigPath); err == nil {
+						config, err := scanning.ParseRepositoryConfig(data)
+						if err != nil {
+							return err
+						}
+						scanner.ApplyRepositoryConfig(config)
+					}
+
+					matchCount, err := scanFiles(scanner, c.Args().Slice(), os.Stdout)
+					if err != nil {
+						return err
+					}
+
+					if matchCount > 0 {
+						return cli.Exit(fmt.Sprintf("%d potential secrets found", matchCount), 1)
+					}
+
+					return nil
+				},
+			},
 		},
 		Action: func(c *cli.Context) error {
 
@@ -121,22 +404,59 @@ func main() {
 			}
 
 			// Get the Pattern store
-			patternStore, err := scanning.NewPatternStore(patternsLocation)
+			patternStore, err := getPatternStore()
 			if err != nil {
 				return err
 			}
 
-			// Setup webhook handlers
-			webHookHandler := handlers.NewWebhookHandler(path, appId, &patternStore, privateKey, secret)
+			// Check the scanner options
+			scannerOptions, err := getScannerOptions()
+			if err != nil {
+				return err
+			}
+
+			// Check where honeytoken alerts are sent
+			if err := securityAlerts.Validate(); err != nil {
+				return err
+			}
+
+			// Setup webhook handlers, whose events are cancelled when Orca shuts down
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			webHookHandler := handlers.NewWebhookHandler(
+				ctx,
+				path,
+				appId,
+				&patternStore,
+				scannerOptions,
+				securityAlerts,
+				eventTimeout,
+				privateKey,
+				secret)
 
 			// Start HTTP webhooks
 			log.Printf("Starting webhooks at port %d\n", port)
 			var address = fmt.Sprintf(":%d", port)
-			if err := http.ListenAndServe(address, webHookHandler); err != nil {
+			server := &http.Server{Addr: address, Handler: webHookHandler}
+
+			// On shutdown, stop accepting events and cancel the ones being handled, then wait for them to record their
+			//	partial results. Shutdown waits for every handler to return, so they must be cancelled first or an event
+			//	without a timeout would keep Orca running forever.
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			shutdown := make(chan error, 1)
+			go func() {
+				<-signals
+				log.Println("Shutting down...")
+				cancel()
+				shutdown <- server.Shutdown(context.Background())
+			}()
+
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
 				return err
 			}
 
-			return nil
+			return <-shutdown
 		},
 	}
 
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"Orca/pkg/crypto"
+	"Orca/pkg/scanning"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io/ioutil"
+	"os"
+)
+
+func patternsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "patterns",
+		Usage: "Manage the patterns Orca searches for",
+		Subcommands: []*cli.Command{
+			patternsImportCommand(),
+			patternsSignCommand(),
+			patternsLintCommand(),
+		},
+	}
+}
+
+func patternsImportCommand() *cli.Command {
+	var formatName string
+	var output string
+
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Convert gitleaks, git-secrets or detect-secrets rules into Orca patterns",
+		ArgsUsage: "<rules file>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "format",
+				Aliases:     []string{"f"},
+				Usage:       "The format of the rules: gitleaks, git-secrets or detect-secrets. Detected from the file name if not given.",
+				Destination: &formatName,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Usage:       "The file to write the patterns to. Written to standard output if not given.",
+				Destination: &output,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("a rules file to import must be provided")
+			}
+			rulesFile := c.Args().First()
+
+			format := scanning.DetectPatternFormat(rulesFile)
+			if len(formatName) > 0 {
+				var err error
+				if format, err = scanning.ParsePatternFormat(formatName); err != nil {
+					return err
+				}
+			}
+
+			data, err := ioutil.ReadFile(rulesFile)
+			if err != nil {
+				return err
+			}
+
+			result, err := scanning.ImportPatterns(format, data)
+			if err != nil {
+				return err
+			}
+
+			patternsJson, err := marshalPatterns(result.Patterns)
+			if err != nil {
+				return err
+			}
+
+			if len(output) > 0 {
+				if err := ioutil.WriteFile(output, patternsJson, 0644); err != nil {
+					return err
+				}
+			} else {
+				fmt.Print(string(patternsJson))
+			}
+
+			// Report what couldn't be converted separately from the patterns
+			fmt.Fprintf(os.Stderr, "Imported %d patterns from %s (%s)\n", len(result.Patterns), rulesFile, format)
+			if len(result.Skipped) > 0 {
+				fmt.Fprintf(os.Stderr, "%d rules could not be converted:\n", len(result.Skipped))
+				for _, skipped := range result.Skipped {
+					fmt.Fprintf(os.Stderr, "  %s: %s\n", skipped.Rule, skipped.Reason)
+				}
+			}
+
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+
+			return nil
+		},
+	}
+}
+
+// marshalPatterns formats patterns as a patterns.json file, leaving characters like < and > unescaped so that the
+// regexes stay readable
+func marshalPatterns(patterns []scanning.SearchPattern) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(patterns); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func patternsSignCommand() *cli.Command {
+	var privateKeyFile string
+	var output string
+
+	return &cli.Command{
+		Name:      "sign",
+		Usage:     "Sign a patterns file so that it can be verified with --patterns-public-key",
+		ArgsUsage: "<patterns file>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "key",
+				Aliases:     []string{"k"},
+				Usage:       "An Ed25519 private key in PEM format, e.g. from \"openssl genpkey -algorithm ed25519\".",
+				Required:    true,
+				Destination: &privateKeyFile,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Usage:       "The file to write the signature to. Defaults to the patterns file with a .sig extension.",
+				Destination: &output,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("a patterns file to sign must be provided")
+			}
+			patternsFile := c.Args().First()
+
+			privateKey, err := crypto.DecodeSigningKeyFromFile(privateKeyFile)
+			if err != nil {
+				return err
+			}
+
+			data, err := ioutil.ReadFile(patternsFile)
+			if err != nil {
+				return err
+			}
+
+			// Don't sign anything which would fail to load
+			if _, err := scanning.ImportPatterns(scanning.DetectPatternFormat(patternsFile), data); err != nil {
+				return err
+			}
+
+			if len(output) == 0 {
+				output = scanning.SignatureLocation(patternsFile)
+			}
+
+			if err := ioutil.WriteFile(output, crypto.SignDetached(data, privateKey), 0644); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Signature written to %s\n", output)
+
+			return nil
+		},
+	}
+}
+
+func patternsLintCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lint",
+		Usage:     "Check a patterns file, showing each pattern with its fragments expanded",
+		ArgsUsage: "<patterns file>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("a patterns file to lint must be provided")
+			}
+
+			data, err := ioutil.ReadFile(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			file, err := scanning.ParsePatternFile(data)
+			if err != nil {
+				return err
+			}
+
+			problems := 0
+			for _, pattern := range file.Patterns {
+				fmt.Printf("%s\n", pattern.Kind)
+
+				expanded, err := file.ExpandPattern(pattern)
+				if err == nil {
+					err = expanded.Validate()
+				}
+				if err != nil {
+					fmt.Printf("  error: %v\n", err)
+					problems++
+					continue
+				}
+
+				fmt.Printf("  pattern: %s\n", expanded.Pattern)
+				for _, exclusion := range expanded.Exclusions {
+					fmt.Printf("  exclusion: %s\n", exclusion)
+				}
+			}
+
+			for _, name := range file.UnusedFragments() {
+				fmt.Fprintf(os.Stderr, "Warning: fragment \"%s\" is not used\n", name)
+			}
+
+			if problems > 0 {
+				return cli.Exit(fmt.Sprintf("%d of %d patterns are invalid", problems, len(file.Patterns)), 1)
+			}
+
+			fmt.Fprintf(os.Stderr, "%d patterns and %d fragments are valid\n", len(file.Patterns), len(file.Fragments))
+
+			return nil
+		},
+	}
+}
+
+// decodePublicKeys decodes public keys given as file paths or base64 encoded keys
+func decodePublicKeys(values []string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, value := range values {
+		raw := []byte(value)
+		if info, err := os.Stat(value); err == nil && !info.IsDir() {
+			if raw, err = ioutil.ReadFile(value); err != nil {
+				return nil, err
+			}
+		}
+
+		key, err := crypto.DecodeVerificationKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patterns public key: %v", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"Orca/pkg/scanning"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// scanFiles scans local files and writes any matches to the output, returning the number of matches found. A path of
+// - scans stdin.
+func scanFiles(scanner *scanning.Scanner, paths []string, output io.Writer) (int, error) {
+	matchCount := 0
+	for _, path := range paths {
+		count, err := scanFile(scanner, path, output)
+		matchCount += count
+		if err != nil {
+			return matchCount, err
+		}
+	}
+
+	return matchCount, nil
+}
+
+// scanFile streams the matches in a file to the output as they are found
+func scanFile(scanner *scanning.Scanner, path string, output io.Writer) (int, error) {
+	reader := io.Reader(os.Stdin)
+	scanPath := ""
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		reader = file
+		scanPath = path
+	}
+
+	stream, err := scanner.ScanFileReader(context.Background(), scanPath, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	matchCount := 0
+	for match := range stream.Matches() {
+		matchCount++
+		fmt.Fprintf(output, "%s:%d: %s (%s): %s (%d characters)\n", path, match.LineNumber, match.Kind, match.Severity,
+			match.Preview, match.Length)
+		if match.Plugin != "" {
+			fmt.Fprintf(output, "\tplugin: %s\n", match.Plugin)
+		}
+		if match.Honeytoken != "" {
+			fmt.Fprintf(output, "\thoneytoken: %s\n", match.Honeytoken)
+		}
+		if match.Location != "" {
+			fmt.Fprintf(output, "\tlocation: %s\n", match.Location)
+		}
+		if match.CodeContext != "" {
+			fmt.Fprintf(output, "\tfound in: %s\n", match.CodeContext)
+		}
+		if match.Triage != nil {
+			fmt.Fprintf(output, "\ttriage: %s\n", match.Triage.Describe())
+		}
+		if len(match.SecondaryKinds) > 0 {
+			fmt.Fprintf(output, "\talso matched: %s\n", strings.Join(match.SecondaryKinds, ", "))
+		}
+		if match.ContextPreview != "" {
+			fmt.Fprintf(output, "\t%s\n", match.ContextPreview)
+		}
+		if match.Remediation != "" {
+			fmt.Fprintf(output, "\tremediation: %s\n", match.Remediation)
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return matchCount, err
+	}
+
+	for _, reason := range stream.IncompleteReasons() {
+		fmt.Fprintf(output, "%s: incomplete scan: %s\n", path, reason)
+	}
+
+	return matchCount, nil
+}
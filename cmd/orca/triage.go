@@ -0,0 +1,107 @@
+package main
+
+import (
+	"Orca/pkg/scanning"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func triageCommand(getScanner func() (*scanning.Scanner, error), triageFile *string) *cli.Command {
+	return &cli.Command{
+		Name:  "triage",
+		Usage: "Record decisions on matches, which the triage model learns from",
+		Description: "Decisions are recorded in the triage file with the match's kind, path, surrounding names and the " +
+			"shape of its value, but never the value itself.",
+		Subcommands: []*cli.Command{
+			triageDecisionCommand(getScanner, triageFile, "false-positive", true),
+			triageDecisionCommand(getScanner, triageFile, "true-positive", false),
+		},
+	}
+}
+
+func triageDecisionCommand(
+	getScanner func() (*scanning.Scanner, error),
+	triageFile *string,
+	name string,
+	falsePositive bool) *cli.Command {
+
+	var kind string
+
+	return &cli.Command{
+		Name:      name,
+		Usage:     fmt.Sprintf("Record that a match is a %s", strings.Replace(name, "-", " ", 1)),
+		ArgsUsage: "<file>:<line>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "kind",
+				Usage:       "The kind of match to record, when more than one was found on the line.",
+				Destination: &kind,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if len(*triageFile) == 0 {
+				return errors.New("a triage file must be provided with --triage-file")
+			}
+
+			if c.NArg() != 1 {
+				return errors.New("the match's file and line number must be provided as <file>:<line>")
+			}
+
+			separator := strings.LastIndex(c.Args().First(), ":")
+			if separator < 1 {
+				return fmt.Errorf("invalid match \"%s\", expected <file>:<line>", c.Args().First())
+			}
+			path := c.Args().First()[:separator]
+			lineNumber, err := strconv.Atoi(c.Args().First()[separator+1:])
+			if err != nil {
+				return fmt.Errorf("invalid line number in \"%s\"", c.Args().First())
+			}
+
+			scanner, err := getScanner()
+			if err != nil {
+				return err
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			result, err := scanner.ScanFileContent(path, string(content))
+			if err != nil {
+				return err
+			}
+
+			var matches []scanning.LineMatch
+			for _, match := range result.Matches {
+				if match.LineNumber == lineNumber && (kind == "" || match.Kind == kind) {
+					matches = append(matches, match)
+				}
+			}
+
+			if len(matches) == 0 {
+				return fmt.Errorf("no matches were found on line %d of %s", lineNumber, path)
+			} else if len(matches) > 1 {
+				var kinds []string
+				for _, match := range matches {
+					kinds = append(kinds, match.Kind)
+				}
+				return fmt.Errorf("%d matches were found on line %d of %s, choose one with --kind: %s",
+					len(matches), lineNumber, path, strings.Join(kinds, ", "))
+			}
+
+			decision := scanning.NewTriageDecision(path, matches[0].Match, falsePositive)
+			if err := scanning.RecordTriageDecision(*triageFile, decision); err != nil {
+				return err
+			}
+
+			fmt.Printf("Recorded %s as a %s in %s\n", matches[0].Kind, strings.Replace(name, "-", " ", 1), *triageFile)
+
+			return nil
+		},
+	}
+}
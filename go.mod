@@ -3,7 +3,9 @@ module Orca
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/dgrijalva/jwt-go v1.0.2
 	github.com/google/go-github/v33 v33.0.0
 	github.com/urfave/cli/v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -4,16 +4,17 @@ import (
 	"Orca/pkg/crypto"
 	"context"
 	"crypto/rsa"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/go-github/v33/github"
 	"net/http"
 	"time"
 )
 
-func GetGitHubApiClient(installationId int64, appId int, privateKey *rsa.PrivateKey) (*github.Client, error) {
+func GetGitHubApiClient(ctx context.Context, installationId int64, appId int, privateKey *rsa.PrivateKey) (*github.Client, error) {
 
 	// Get the GitHub App Installation access token
-	accessToken, err := getInstallationAccessToken(installationId, appId, privateKey)
+	accessToken, err := getInstallationAccessToken(ctx, installationId, appId, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +27,31 @@ func GetGitHubApiClient(installationId int64, appId int, privateKey *rsa.Private
 	return client, nil
 }
 
-func getInstallationAccessToken(installationId int64, appId int, privateKey *rsa.PrivateKey) (*string, error) {
+// GetRepositoryInstallationClient creates a client for the app's installation on a repository, which may belong to a
+// different account than the installation an event came from
+func GetRepositoryInstallationClient(
+	ctx context.Context,
+	owner string,
+	name string,
+	appId int,
+	privateKey *rsa.PrivateKey) (*github.Client, error) {
+
+	// Finding the installation needs the App's JWT rather than an installation access token
+	appToken, err := getAppJsonWebToken(appId, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := getHttpClientWithInjectedToken(*appToken)
+	installation, _, err := github.NewClient(&httpClient).Apps.FindRepositoryInstallation(ctx, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the app's installation on %s/%s: %v", owner, name, err)
+	}
+
+	return GetGitHubApiClient(ctx, installation.GetID(), appId, privateKey)
+}
+
+func getInstallationAccessToken(ctx context.Context, installationId int64, appId int, privateKey *rsa.PrivateKey) (*string, error) {
 
 	// To get the Installation access token, we first need the Apps JWT
 	appToken, err := getAppJsonWebToken(appId, privateKey)
@@ -39,7 +64,7 @@ func getInstallationAccessToken(installationId int64, appId int, privateKey *rsa
 	gitHubClient := github.NewClient(&httpClient)
 
 	// Get the Installation access token
-	tokenResponse, _, err := gitHubClient.Apps.CreateInstallationToken(context.Background(), installationId, nil)
+	tokenResponse, _, err := gitHubClient.Apps.CreateInstallationToken(ctx, installationId, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -5,10 +5,12 @@ import (
 	"encoding/base64"
 	"github.com/google/go-github/v33/github"
 	"log"
+	"sync"
 )
 
 var (
-	cache *inMemoryFileCache
+	cache     *inMemoryFileCache
+	cacheOnce sync.Once
 )
 
 const (
@@ -25,6 +27,10 @@ type GitHubFileQuery struct {
 	CommitSHA string
 	FileName  string
 	Status    FileState
+
+	// Patch is the unified diff of the file in the commit, if known. GitHub omits it for binary and large files.
+	Patch   *string
+	BlobURL string
 }
 
 type File struct {
@@ -37,6 +43,7 @@ type File struct {
 
 // Todo: If this is going to run as a serverless application, then it will make more sense to use Redis or Memcached
 type inMemoryFileCache struct {
+	mutex sync.Mutex
 	files []File
 }
 
@@ -53,11 +60,11 @@ func (cache *inMemoryFileCache) addFile(file File) {
 }
 
 func getFileCache() *inMemoryFileCache {
-	if cache == nil {
+	cacheOnce.Do(func() {
 		cache = &inMemoryFileCache{
 			files: []File{},
 		}
-	}
+	})
 
 	return cache
 }
@@ -83,11 +90,14 @@ func (cache *inMemoryFileCache) getFilesFromCommit(commitSHA string) []*File {
 	return results
 }
 
-func GetFile(query GitHubFileQuery, client *github.Client) (*File, error) {
+func GetFile(ctx context.Context, query GitHubFileQuery, client *github.Client) (*File, error) {
 
-	// Check the cache
+	// Check the cache. Files may be fetched by several scans at once, so the cache is only locked while it is read and
+	//	written, not while a file is being fetched.
 	cache := getFileCache()
+	cache.mutex.Lock()
 	_, file := cache.getFileFromCommit(query.CommitSHA, query.FileName)
+	cache.mutex.Unlock()
 
 	// If not in the cache, then send a request and cache the result for later
 	if file == nil {
@@ -102,7 +112,7 @@ func GetFile(query GitHubFileQuery, client *github.Client) (*File, error) {
 		if query.Status != FileRemoved {
 			log.Printf("%s from %s not available in cache, fetching from API\n", query.FileName, query.CommitSHA)
 			content, _, _, err := client.Repositories.GetContents(
-				context.Background(),
+				ctx,
 				query.RepoOwner,
 				query.RepoName,
 				query.FileName,
@@ -123,7 +133,9 @@ func GetFile(query GitHubFileQuery, client *github.Client) (*File, error) {
 			file.PermalinkURL = *content.HTMLURL
 		}
 
+		cache.mutex.Lock()
 		cache.addFile(*file)
+		cache.mutex.Unlock()
 	} else {
 		log.Printf("%s from %s fetched from cache\n", query.FileName, query.CommitSHA)
 	}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+)
+
+// DecodeSigningKeyFromFile reads an Ed25519 private key in PKCS #8 PEM format, such as one generated by
+// "openssl genpkey -algorithm ed25519"
+func DecodeSigningKeyFromFile(path string) (ed25519.PrivateKey, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("failed to decode PEM block containing private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an Ed25519 key")
+	}
+
+	return signingKey, nil
+}
+
+// DecodeVerificationKey decodes an Ed25519 public key, either in PKIX PEM format or as the base64 encoded key
+func DecodeVerificationKey(raw []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, errors.New("failed to decode PEM block containing public key")
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		verificationKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an Ed25519 key")
+		}
+
+		return verificationKey, nil
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keyBytes) != ed25519.PublicKeySize {
+		return nil, errors.New("public key is not an Ed25519 key")
+	}
+
+	return keyBytes, nil
+}
+
+// SignDetached signs data, returning the base64 encoded signature to be stored alongside it
+func SignDetached(data []byte, key ed25519.PrivateKey) []byte {
+	signature := ed25519.Sign(key, data)
+	encoded := base64.StdEncoding.EncodeToString(signature) + "\n"
+
+	return []byte(encoded)
+}
+
+// VerifyDetached checks a base64 encoded signature of data against each of the trusted keys
+func VerifyDetached(data []byte, encodedSignature []byte, keys []ed25519.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encodedSignature)))
+	if err != nil {
+		return errors.New("signature is not valid base64")
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, data, signature) {
+			return nil
+		}
+	}
+
+	return errors.New("signature does not match any of the trusted keys")
+}
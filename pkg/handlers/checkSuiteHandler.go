@@ -18,18 +18,19 @@ const (
 	checkRunConclusionSuccess checkRunConclusion = "success"
 	checkRunConclusionSkipped checkRunConclusion = "skipped"
 	checkRunConclusionFailure checkRunConclusion = "failure"
+	checkRunConclusionNeutral checkRunConclusion = "neutral"
 )
 
 // BUG: This will trigger a failure even if the issue has been fixed in a more recent commit
 
-func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckSuiteEvent) {
+func (handler *PayloadHandler) HandleCheckSuite(ctx context.Context, checkSuitePayload *github.CheckSuiteEvent) {
 	log.Println("Handling Check Suite request...")
 
 	// Create a new Check Run
 	log.Println("Creating new check run")
 	inProgressString := string(checkRunStatusInProgress)
 	checkRun, _, err := handler.GitHubClient.Checks.CreateCheckRun(
-		context.Background(),
+		ctx,
 		*checkSuitePayload.Repo.Owner.Login,
 		*checkSuitePayload.Repo.Name,
 		github.CreateCheckRunOptions{
@@ -38,7 +39,7 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 			Status:  &inProgressString,
 		})
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
@@ -46,16 +47,24 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 	checkRun.CheckSuite.Repository = checkSuitePayload.Repo
 
 	// Execute the check
+	handler.applyRepositoryConfig(
+		ctx,
+		*checkSuitePayload.Repo.Owner.Login,
+		*checkSuitePayload.Repo.Name,
+		trustedConfigRef(
+			checkSuitePayload.CheckSuite.GetHeadBranch(),
+			checkSuitePayload.Repo.GetDefaultBranch(),
+			checkSuitePayload.CheckSuite.GetBeforeSHA()))
 	if len(checkSuitePayload.CheckSuite.PullRequests) > 0 {
 		for _, pullRequest := range checkSuitePayload.CheckSuite.PullRequests {
 			commits, _, err := handler.GitHubClient.PullRequests.ListCommits(
-				context.Background(),
+				ctx,
 				*checkSuitePayload.Repo.Owner.Login,
 				*checkSuitePayload.Repo.Name,
 				*pullRequest.Number,
 				nil)
 			if err != nil {
-				handler.handleFailure(checkRun, "Failed to list commits from Pull Request", err)
+				handler.handleFailure(ctx, checkRun, "Failed to list commits from Pull Request", err)
 				return
 			}
 
@@ -69,12 +78,12 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 
 				// Todo: Files from commit not available in commit list, need another request...
 				commitWithFiles, _, err := handler.GitHubClient.Repositories.GetCommit(
-					context.Background(),
+					ctx,
 					*checkSuitePayload.Repo.Owner.Login,
 					*checkSuitePayload.Repo.Name,
 					*commitSha)
 				if err != nil {
-					handler.handleFailure(checkRun, "Failed to get commit from Pull Request", err)
+					handler.handleFailure(ctx, checkRun, "Failed to get commit from Pull Request", err)
 					return
 				}
 
@@ -95,26 +104,60 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 						CommitSHA: *commitSha,
 						FileName:  *file.Filename,
 						Status:    fileStatus,
+						Patch:     file.Patch,
+						BlobURL:   file.GetBlobURL(),
 					})
 				}
 			}
 
 			commitScanResults, err := handler.Scanner.CheckFileContentFromQueries(
+				ctx,
 				handler.GitHubClient,
 				fileQueries)
 			if err != nil {
-				handler.handleFailure(checkRun, "Failed to scan commits from Pull Request", err)
+				handler.handleFailure(ctx, checkRun, "Failed to scan commits from Pull Request", err)
 				return
 			}
 
 			if len(commitScanResults) > 0 {
+				recordCtx, cancel := recordingContext()
+				defer cancel()
 
 				// Todo: Once scan results are persisted, only act on new scan results
 
+				// Honeytokens always fail the check, even if they have since been removed. If the security team can't be
+				//	alerted, the check run asks whoever reads it to tell them instead.
+				var alertErr error
+				honeytokenMatches := scanning.HoneytokenMatches(commitScanResults)
+				if len(honeytokenMatches) > 0 {
+					matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+					alertErr = matchHandler.alertHoneytokens(
+						recordCtx,
+						*checkSuitePayload.Repo.Owner.Login,
+						*checkSuitePayload.Repo.Name,
+						fmt.Sprintf("commits of pull request #%d", pullRequest.GetNumber()),
+						fileMatchSightings(honeytokenMatches))
+					if alertErr != nil {
+						log.Printf("ALERT FAILED: %v\n", alertErr)
+					}
+				}
+
 				// If all matches are resolved, pass the check, but reply with a reminder that the matches can still be
 				//	viewed in the commit history
 				var conclusion checkRunConclusion
-				if AllMatchesAreResolved(commitScanResults) {
+				if !scanning.AnyCommitHasMatches(commitScanResults) && !scanning.AnyCommitIsIncomplete(commitScanResults) {
+
+					// Nothing was found, and the results only list the files which were skipped
+					log.Printf("No matches to address in pull request #%d, some files were skipped.\n", pullRequest.Number)
+					_, text := BuildMessage(commitScanResults)
+					handler.completeCheckRun(checkRun, checkRunConclusionSuccess, "No issues detected", &text)
+					return
+				} else if !scanning.AnyCommitHasMatches(commitScanResults) {
+
+					// Nothing was found, but some files could not be scanned in full so we can't be sure
+					log.Printf("Scan of pull request #%d is incomplete. Completing check as neutral.\n", pullRequest.Number)
+					conclusion = checkRunConclusionNeutral
+				} else if AllMatchesAreResolved(commitScanResults) && len(honeytokenMatches) == 0 {
 					log.Printf("Matches found but resolved in pull request #%d. Passing check with reminder.\n", pullRequest.Number)
 					conclusion = checkRunConclusionSuccess
 
@@ -124,7 +167,7 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 					body += fmt.Sprintf("See the [Orca check results](%s) for more information.\n", *checkRun.HTMLURL)
 					body += "If any sensitive information is in the history, please make sure it is addressed appropriately." // Todo: Reword this line
 					_, _, err := handler.GitHubClient.Issues.CreateComment(
-						context.Background(),
+						recordCtx,
 						*checkSuitePayload.Repo.Owner.Login,
 						*checkSuitePayload.Repo.Name,
 						*pullRequest.Number,
@@ -132,7 +175,7 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 							Body: &body,
 						})
 					if err != nil {
-						handler.handleFailure(checkRun, "Failed to reply to Pull Request with commit history warning", err)
+						handler.handleFailure(recordCtx, checkRun, "Failed to reply to Pull Request with commit history warning", err)
 						return
 					}
 				} else {
@@ -141,6 +184,12 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 				}
 
 				title, text := BuildMessage(commitScanResults)
+				if conclusion == checkRunConclusionNeutral {
+					title = "Some files could not be scanned in full"
+				}
+				if alertErr != nil {
+					text += honeytokenAlertFailedNotice
+				}
 				handler.completeCheckRun(checkRun, conclusion, title, &text)
 
 				return
@@ -161,7 +210,19 @@ func (handler *PayloadHandler) HandleCheckSuite(checkSuitePayload *github.CheckS
 	}
 }
 
-func (handler *PayloadHandler) handleFailure(checkRun *github.CheckRun, summary string, err error) {
+// handleFailure fails the check run and stops Orca. If the event was cancelled or ran out of time, the check run is
+// completed as neutral instead, as nothing was found to be wrong.
+func (handler *PayloadHandler) handleFailure(
+	ctx context.Context,
+	checkRun *github.CheckRun,
+	summary string,
+	err error) {
+	if ctx.Err() != nil {
+		handler.completeCheckRun(checkRun, checkRunConclusionNeutral, fmt.Sprintf("%s: %v", summary, ctx.Err()), nil)
+		log.Printf("Stopped handling check suite: %v\n", err)
+		return
+	}
+
 	handler.updateCheckRun(
 		checkRun,
 		checkRunStatusCompleted,
@@ -180,6 +241,8 @@ func (handler *PayloadHandler) completeCheckRun(checkRun *github.CheckRun, concl
 		text)
 }
 
+// updateCheckRun records the check run's results with a recording context rather than the event's, so that a check run
+// is still completed with the partial results when the event is cancelled or runs out of time
 func (handler *PayloadHandler) updateCheckRun(
 	checkRun *github.CheckRun,
 	status checkRunStatus,
@@ -191,8 +254,11 @@ func (handler *PayloadHandler) updateCheckRun(
 	conclusionString := string(conclusion)
 	outputTitle := "Orca Checks"
 
+	ctx, cancel := recordingContext()
+	defer cancel()
+
 	_, _, err := handler.GitHubClient.Checks.UpdateCheckRun(
-		context.Background(),
+		ctx,
 		*checkRun.CheckSuite.Repository.Owner.Login,
 		*checkRun.CheckSuite.Repository.Name,
 		*checkRun.ID,
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"Orca/pkg/scanning"
+	"context"
+	"fmt"
+	"github.com/google/go-github/v33/github"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// honeytokenRedactionNotice is added to content which had a honeytoken redacted from it, so that the redaction is
+// never silent. If the security team couldn't be alerted, the notices ask whoever sees them to tell the team instead.
+const (
+	honeytokenRedactionNotice = "\n\n> :rotating_light: A honeytoken was found here and has been redacted. " +
+		"The security team has been alerted."
+	honeytokenRedactionAlertFailedNotice = "\n\n> :rotating_light: A honeytoken was found here and has been " +
+		"redacted, but the security team could not be alerted automatically. Please tell them about it straight away."
+	honeytokenAlertFailedNotice = "\n\n> :rotating_light: Honeytokens were found, but the security team could " +
+		"not be alerted automatically. Please tell them about it straight away."
+)
+
+// honeytokenAlertLabel is added to alert issues so that existing alerts can be found again
+const honeytokenAlertLabel = "honeytoken"
+
+// honeytokenAlertMarkerRegex finds the hidden marker an alert issue has for each sighting it lists
+var honeytokenAlertMarkerRegex = regexp.MustCompile(`<!-- honeytoken-alert: (\S+) -->`)
+
+// AlertClientFactory creates a client for the app's installation on the security alert repository. The client an
+// event came with can only reach the repositories of that event's installation.
+type AlertClientFactory func(ctx context.Context, owner string, name string) (*github.Client, error)
+
+// SecurityAlertOptions configures where alerts about honeytokens are sent
+type SecurityAlertOptions struct {
+
+	// Repository is the owner/name of the repository alerts are opened as issues in. The app must be installed on it.
+	Repository string
+
+	// Team is mentioned in alerts, e.g. OctopusDeploy/security
+	Team string
+}
+
+func (options *SecurityAlertOptions) Validate() error {
+	if options.Repository != "" {
+		parts := strings.Split(options.Repository, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid security alert repository \"%s\", expected owner/name", options.Repository)
+		}
+	}
+
+	if options.Team != "" && !strings.Contains(options.Team, "/") {
+		return fmt.Errorf("invalid security team \"%s\", expected organisation/team", options.Team)
+	}
+
+	return nil
+}
+
+// honeytokenSighting is where a honeytoken was found. The key identifies the sighting across reruns of the same
+// event, from the honeytoken's fingerprint and the commit or content it was found in.
+type honeytokenSighting struct {
+	name    string
+	url     string
+	preview string
+	key     string
+}
+
+func lineMatchSightings(url string, matches []scanning.LineMatch) []honeytokenSighting {
+	var sightings []honeytokenSighting
+	for _, match := range matches {
+		if match.Honeytoken != "" {
+			sightings = append(sightings, honeytokenSighting{
+				name:    match.Honeytoken,
+				url:     url,
+				preview: fmt.Sprintf("line %d: `%s`", match.LineNumber, match.Preview),
+				key:     match.HoneytokenFingerprint() + "@" + url,
+			})
+		}
+	}
+
+	return sightings
+}
+
+func fileMatchSightings(matches []scanning.FileContentMatch) []honeytokenSighting {
+	var sightings []honeytokenSighting
+	for _, match := range matches {
+		sightings = append(sightings, honeytokenSighting{
+			name:    match.Honeytoken,
+			url:     fmt.Sprintf("%s#L%d", match.PermalinkURL, match.LineNumber),
+			preview: fmt.Sprintf("`%s` in %s: `%s`", match.Path, match.CommitSHA, match.Preview),
+			key:     match.HoneytokenFingerprint() + "@" + match.CommitSHA,
+		})
+	}
+
+	return sightings
+}
+
+// alertHoneytokens tells the security team that honeytokens were found. Alerts are always logged, and are opened as an
+// issue in the security alert repository if one is configured, unless an earlier alert already lists every sighting.
+// The error is returned so that the caller can ask whoever sees the event to tell the security team instead.
+func (matchHandler *MatchHandler) alertHoneytokens(
+	ctx context.Context,
+	repoOwner string,
+	repoName string,
+	surface string,
+	sightings []honeytokenSighting) error {
+
+	if len(sightings) == 0 {
+		return nil
+	}
+
+	for _, sighting := range sightings {
+		log.Printf("ALERT: Honeytoken %s found in the %s of %s/%s: %s\n",
+			sighting.name, surface, repoOwner, repoName, sighting.url)
+	}
+
+	alerts := matchHandler.SecurityAlerts
+	if alerts.Repository == "" {
+		log.Println("No security alert repository is configured, honeytoken alerts have only been logged")
+		return nil
+	}
+
+	parts := strings.SplitN(alerts.Repository, "/", 2)
+	client, err := matchHandler.AlertClients(ctx, parts[0], parts[1])
+	if err != nil {
+		return fmt.Errorf("could not open honeytoken alert in %s: %v", alerts.Repository, err)
+	}
+
+	// Check suites are rerun and content is edited, so the same sighting can be found many times
+	sightings = unalertedSightings(ctx, client, parts[0], parts[1], sightings)
+	if len(sightings) == 0 {
+		log.Printf("Honeytokens have already been alerted in %s\n", alerts.Repository)
+		return nil
+	}
+
+	title, body := buildHoneytokenAlert(repoOwner, repoName, surface, alerts.Team, sightings)
+	issue, _, err := client.Issues.Create(
+		ctx,
+		parts[0],
+		parts[1],
+		&github.IssueRequest{
+			Title:  &title,
+			Body:   &body,
+			Labels: &[]string{honeytokenAlertLabel},
+		})
+	if err != nil {
+		return fmt.Errorf("could not open honeytoken alert in %s: %v", alerts.Repository, err)
+	}
+
+	log.Printf("Honeytoken alert opened as %s#%d\n", alerts.Repository, issue.GetNumber())
+
+	return nil
+}
+
+// unalertedSightings drops the sightings which an existing alert already lists. If the existing alerts can't be
+// listed, every sighting is kept, as a duplicate alert is better than a missing one.
+func unalertedSightings(
+	ctx context.Context,
+	client *github.Client,
+	owner string,
+	name string,
+	sightings []honeytokenSighting) []honeytokenSighting {
+
+	alerted := map[string]bool{}
+	options := &github.IssueListByRepoOptions{
+		State:       "all",
+		Labels:      []string{honeytokenAlertLabel},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, response, err := client.Issues.ListByRepo(ctx, owner, name, options)
+		if err != nil {
+			log.Printf("Could not list existing honeytoken alerts in %s/%s: %v\n", owner, name, err)
+			return sightings
+		}
+
+		for _, issue := range issues {
+			for _, marker := range honeytokenAlertMarkerRegex.FindAllStringSubmatch(issue.GetBody(), -1) {
+				alerted[marker[1]] = true
+			}
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+		options.Page = response.NextPage
+	}
+
+	var result []honeytokenSighting
+	for _, sighting := range sightings {
+		if !alerted[sighting.key] {
+			result = append(result, sighting)
+		}
+	}
+
+	return result
+}
+
+func buildHoneytokenAlert(
+	repoOwner string,
+	repoName string,
+	surface string,
+	team string,
+	sightings []honeytokenSighting) (string, string) {
+
+	title := fmt.Sprintf("Honeytoken found in %s/%s", repoOwner, repoName)
+
+	var body string
+	if team != "" {
+		body += fmt.Sprintf("@%s\n\n", team)
+	}
+
+	body += fmt.Sprintf(
+		"Honeytokens from the registry have been found in the %s of %s/%s.\n\n",
+		surface,
+		repoOwner,
+		repoName)
+	for _, sighting := range sightings {
+		body += fmt.Sprintf("- **%s** at %s (%s)\n", sighting.name, sighting.url, sighting.preview)
+	}
+
+	// Each sighting is marked so that reruns don't alert it again
+	body += "\n"
+	for _, sighting := range sightings {
+		body += fmt.Sprintf("<!-- honeytoken-alert: %s -->\n", sighting.key)
+	}
+
+	return title, body
+}
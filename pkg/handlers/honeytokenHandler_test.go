@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"Orca/pkg/caching"
+	"Orca/pkg/scanning"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/go-github/v33/github"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// alertServer is a GitHub API which records the requests made to it. Opening issues in the security alert repository
+// fails unless alertsWork is set, and existingAlert is the body of the one alert already in it, if any.
+type alertServer struct {
+	mutex         sync.Mutex
+	requests      []string
+	bodies        []string
+	alertsWork    bool
+	existingAlert string
+}
+
+func (server *alertServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	server.mutex.Lock()
+	server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+	server.bodies = append(server.bodies, body.Body)
+	server.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasPrefix(r.URL.Path, "/repos/security/alerts/") {
+		switch {
+		case !server.alertsWork:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			issues := []*github.Issue{}
+			if server.existingAlert != "" {
+				issues = append(issues, &github.Issue{Number: github.Int(1), Body: github.String(server.existingAlert)})
+			}
+			_ = json.NewEncoder(w).Encode(issues)
+		default:
+			_, _ = w.Write([]byte(`{"number": 2}`))
+		}
+		return
+	}
+
+	_, _ = w.Write([]byte(`{"number": 1}`))
+}
+
+func TestHoneytokenAlertFailures(t *testing.T) {
+	matches := findTestHoneytoken(t)
+	owner := &github.User{Login: github.String("octo"), Name: github.String("octo")}
+	repository := &github.Repository{Owner: owner, Name: github.String("app")}
+
+	t.Run("issue", func(t *testing.T) {
+		server := &alertServer{}
+		matchHandler := newTestMatchHandler(t, server, nil)
+
+		err := matchHandler.HandleMatchesFromIssue(context.Background(), &github.IssuesEvent{
+			Repo:  repository,
+			Issue: &github.Issue{Number: github.Int(7), Body: github.String(testHoneytokenContent), Repository: repository},
+		}, &scanning.IssueScanResult{Matches: matches})
+		if err != nil {
+			t.Fatalf("expected the issue to be redacted but got %v", err)
+		}
+
+		expected := []string{
+			"GET /repos/security/alerts/issues",
+			"POST /repos/security/alerts/issues",
+			"PATCH /repos/octo/app/issues/7",
+		}
+		if !reflect.DeepEqual(server.requests, expected) {
+			t.Errorf("expected %v but got %v", expected, server.requests)
+		}
+
+		// The failure is surfaced in the redacted issue, so that someone can tell the security team
+		if redacted := server.bodies[2]; strings.Contains(redacted, "tok_canary01") ||
+			!strings.HasSuffix(redacted, honeytokenRedactionAlertFailedNotice) {
+			t.Errorf("expected the issue to be redacted with a notice but got %q", redacted)
+		}
+	})
+
+	t.Run("push", func(t *testing.T) {
+		server := &alertServer{}
+		matchHandler := newTestMatchHandler(t, server, nil)
+
+		err := matchHandler.HandleMatchesFromPush(context.Background(), newTestPushEvent(owner), []scanning.CommitScanResult{{
+			Commit:  "c1",
+			Matches: []scanning.FileContentMatch{{File: caching.File{Path: "a.txt"}, LineMatch: matches[0]}},
+		}})
+		if err != nil {
+			t.Fatalf("expected the report issue to be opened but got %v", err)
+		}
+
+		// The alert is sent before the report issue, so that it doesn't depend on the issue being opened
+		expected := []string{
+			"GET /repos/security/alerts/issues",
+			"POST /repos/security/alerts/issues",
+			"POST /repos/octo/app/issues",
+		}
+		if !reflect.DeepEqual(server.requests, expected) {
+			t.Errorf("expected %v but got %v", expected, server.requests)
+		}
+		if report := server.bodies[2]; !strings.HasSuffix(report, honeytokenAlertFailedNotice) {
+			t.Errorf("expected the report to say the alert failed but got %q", report)
+		}
+	})
+
+	t.Run("no installation on the alert repository", func(t *testing.T) {
+		server := &alertServer{alertsWork: true}
+		matchHandler := newTestMatchHandler(t, server, errors.New("not installed"))
+
+		err := matchHandler.alertHoneytokens(context.Background(), "octo", "app", "issue #7",
+			lineMatchSightings("https://github.com/octo/app/issues/7", matches))
+		if err == nil || !strings.Contains(err.Error(), "not installed") {
+			t.Errorf("expected the missing installation to be returned but got %v", err)
+		}
+		if len(server.requests) != 0 {
+			t.Errorf("expected the event's own client not to be used for the alert but got %v", server.requests)
+		}
+	})
+}
+
+func TestHoneytokenAlertsAreDeduplicated(t *testing.T) {
+	matches := findTestHoneytoken(t)
+	sightings := fileMatchSightings([]scanning.FileContentMatch{{
+		File:      caching.File{CommitSHA: "c1", Path: "a.txt"},
+		LineMatch: matches[0],
+	}})
+	_, existingAlert := buildHoneytokenAlert("octo", "app", "commits", "", sightings)
+
+	tests := []struct {
+		name          string
+		existingAlert string
+		expected      []string
+	}{
+		{
+			"new sighting",
+			"",
+			[]string{"GET /repos/security/alerts/issues", "POST /repos/security/alerts/issues"},
+		},
+		{
+			"already alerted",
+			existingAlert,
+			[]string{"GET /repos/security/alerts/issues"},
+		},
+		{
+			"alerted in another commit",
+			strings.ReplaceAll(existingAlert, "@c1", "@c0"),
+			[]string{"GET /repos/security/alerts/issues", "POST /repos/security/alerts/issues"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &alertServer{alertsWork: true, existingAlert: test.existingAlert}
+			matchHandler := newTestMatchHandler(t, server, nil)
+
+			if err := matchHandler.alertHoneytokens(context.Background(), "octo", "app", "commits", sightings); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(server.requests, test.expected) {
+				t.Errorf("expected %v but got %v", test.expected, server.requests)
+			}
+		})
+	}
+}
+
+const testHoneytokenContent = "leaked tok_canary01 here"
+
+func findTestHoneytoken(t *testing.T) []scanning.LineMatch {
+	scanner := &scanning.Scanner{
+		Honeytokens: []scanning.Honeytoken{{Name: "canary", Fingerprint: scanning.HoneytokenFingerprint("tok_canary01")}},
+		Detectors:   scanning.DetectorOptions{Disabled: true},
+	}
+
+	matches, err := scanner.CheckContent(context.Background(), testHoneytokenContent)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected the honeytoken to be found but got %v %v", matches, err)
+	}
+
+	return matches
+}
+
+func newTestPushEvent(owner *github.User) *github.PushEvent {
+	return &github.PushEvent{
+		Ref:    github.String("refs/heads/main"),
+		Repo:   &github.PushEventRepository{Owner: owner, Name: github.String("app")},
+		Pusher: owner,
+	}
+}
+
+// newTestMatchHandler makes a match handler whose clients all call the server, unless the alert repository's client
+// can't be created
+func newTestMatchHandler(t *testing.T, server *alertServer, alertClientErr error) *MatchHandler {
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(httpServer.URL + "/")
+
+	alertClients := func(ctx context.Context, owner string, name string) (*github.Client, error) {
+		if alertClientErr != nil {
+			return nil, alertClientErr
+		}
+
+		return client, nil
+	}
+
+	return NewMatchHandler(client, SecurityAlertOptions{Repository: "security/alerts"}, alertClients)
+}
@@ -6,27 +6,50 @@ import (
 	"fmt"
 	"github.com/google/go-github/v33/github"
 	"log"
+	"strings"
+	"unicode/utf8"
 )
 
 type MatchHandler struct {
 	GitHubApiClient *github.Client
+	SecurityAlerts  SecurityAlertOptions
+	AlertClients    AlertClientFactory
 }
 
-func NewMatchHandler(gitHubApiClient *github.Client) *MatchHandler {
+func NewMatchHandler(
+	gitHubApiClient *github.Client,
+	securityAlerts SecurityAlertOptions,
+	alertClients AlertClientFactory) *MatchHandler {
 	return &MatchHandler{
 		GitHubApiClient: gitHubApiClient,
+		SecurityAlerts:  securityAlerts,
+		AlertClients:    alertClients,
 	}
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromPush(
+	ctx context.Context,
 	pushPayload *github.PushEvent,
 	results []scanning.CommitScanResult) error {
 
-	// Open a new issue
+	// Honeytokens are alerted on first, so that the security team hears about them even if the issue can't be opened.
+	//	If they can't be alerted, the issue asks the pusher to tell them instead.
 	title, body := BuildMessage(results)
+	err := matchHandler.alertHoneytokens(
+		ctx,
+		*pushPayload.Repo.Owner.Login,
+		*pushPayload.Repo.Name,
+		"commits pushed to "+pushPayload.GetRef(),
+		fileMatchSightings(scanning.HoneytokenMatches(results)))
+	if err != nil {
+		log.Printf("ALERT FAILED: %v\n", err)
+		body += honeytokenAlertFailedNotice
+	}
+
+	// Open a new issue
 	log.Printf("Opening a new issue \"%s\"\n", title)
 	issue, _, err := matchHandler.GitHubApiClient.Issues.Create(
-		context.Background(),
+		ctx,
 		*pushPayload.Repo.Owner.Login,
 		*pushPayload.Repo.Name,
 		&github.IssueRequest{
@@ -44,15 +67,23 @@ func (matchHandler *MatchHandler) HandleMatchesFromPush(
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromIssue(
+	ctx context.Context,
 	issue *github.IssuesEvent,
 	result *scanning.IssueScanResult) error {
 
 	log.Printf("Redacting matches from #%d\n", issue.Issue.Number)
-	newBody := redactMatchesFromContent(*issue.Issue.Body, result.Matches, '*')
+	newBody := matchHandler.redactContent(
+		ctx,
+		*issue.Repo.Owner.Login,
+		*issue.Repo.Name,
+		fmt.Sprintf("issue #%d", issue.Issue.GetNumber()),
+		issue.Issue.GetHTMLURL(),
+		*issue.Issue.Body,
+		result.Matches)
 
 	// Replace the issue body with the new body with redacted matches
 	_, _, err := matchHandler.GitHubApiClient.Issues.Edit(
-		context.Background(),
+		ctx,
 		*issue.Issue.Repository.Owner.Login,
 		*issue.Issue.Repository.Name,
 		*issue.Issue.Number,
@@ -68,15 +99,23 @@ func (matchHandler *MatchHandler) HandleMatchesFromIssue(
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromIssueComment(
+	ctx context.Context,
 	issue *github.IssueCommentEvent,
 	result *scanning.IssueScanResult) error {
 
 	log.Printf("Redacting matches from #%d (comment %d)\n", issue.Issue.Number, issue.Comment.ID)
-	newBody := redactMatchesFromContent(*issue.Comment.Body, result.Matches, '*')
+	newBody := matchHandler.redactContent(
+		ctx,
+		*issue.Repo.Owner.Login,
+		*issue.Repo.Name,
+		fmt.Sprintf("comment on issue #%d", issue.Issue.GetNumber()),
+		issue.Comment.GetHTMLURL(),
+		*issue.Comment.Body,
+		result.Matches)
 
 	// Replace the issue body with the new body with redacted matches
 	_, _, err := matchHandler.GitHubApiClient.Issues.EditComment(
-		context.Background(),
+		ctx,
 		*issue.Repo.Owner.Login,
 		*issue.Repo.Name,
 		*issue.Comment.ID,
@@ -92,16 +131,24 @@ func (matchHandler *MatchHandler) HandleMatchesFromIssueComment(
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromPullRequest(
+	ctx context.Context,
 	request *github.PullRequestEvent,
 	result *scanning.PullRequestScanResult) error {
 
 	log.Printf("Redacting matches from #%d\n", request.PullRequest.Number)
 
-	newBody := redactMatchesFromContent(*request.PullRequest.Body, result.Matches, '*')
+	newBody := matchHandler.redactContent(
+		ctx,
+		*request.Repo.Owner.Login,
+		*request.Repo.Name,
+		fmt.Sprintf("pull request #%d", request.PullRequest.GetNumber()),
+		request.PullRequest.GetHTMLURL(),
+		*request.PullRequest.Body,
+		result.Matches)
 
 	// Replace the pull request body with new body with redacted matches
 	_, _, err := matchHandler.GitHubApiClient.PullRequests.Edit(
-		context.Background(),
+		ctx,
 		*request.Repo.Owner.Login,
 		*request.Repo.Name,
 		*request.PullRequest.Number,
@@ -117,16 +164,24 @@ func (matchHandler *MatchHandler) HandleMatchesFromPullRequest(
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromPullRequestReview(
+	ctx context.Context,
 	request *github.PullRequestReviewEvent,
 	result *scanning.PullRequestReviewScanResult) error {
 
 	log.Printf("Redacting matches from #%d (review %d)\n", request.PullRequest.Number, request.Review.ID)
 
-	newBody := redactMatchesFromContent(*request.Review.Body, result.Matches, '*')
+	newBody := matchHandler.redactContent(
+		ctx,
+		*request.Repo.Owner.Login,
+		*request.Repo.Name,
+		fmt.Sprintf("review of pull request #%d", request.PullRequest.GetNumber()),
+		request.Review.GetHTMLURL(),
+		*request.Review.Body,
+		result.Matches)
 
 	// Replace the pull request body with new body with redacted matches
 	_, _, err := matchHandler.GitHubApiClient.PullRequests.UpdateReview(
-		context.Background(),
+		ctx,
 		*request.Repo.Owner.Login,
 		*request.Repo.Name,
 		*request.PullRequest.Number,
@@ -141,6 +196,7 @@ func (matchHandler *MatchHandler) HandleMatchesFromPullRequestReview(
 }
 
 func (matchHandler *MatchHandler) HandleMatchesFromPullRequestReviewComment(
+	ctx context.Context,
 	request *github.PullRequestReviewCommentEvent,
 	result *scanning.PullRequestReviewCommentScanResult) error {
 
@@ -150,11 +206,18 @@ func (matchHandler *MatchHandler) HandleMatchesFromPullRequestReviewComment(
 		request.Comment.InReplyTo,
 		request.Comment.ID)
 
-	newBody := redactMatchesFromContent(*request.Comment.Body, result.Matches, '*')
+	newBody := matchHandler.redactContent(
+		ctx,
+		*request.Repo.Owner.Login,
+		*request.Repo.Name,
+		fmt.Sprintf("review comment on pull request #%d", request.PullRequest.GetNumber()),
+		request.Comment.GetHTMLURL(),
+		*request.Comment.Body,
+		result.Matches)
 
 	// Replace the pull request body with new body with redacted matches
 	_, _, err := matchHandler.GitHubApiClient.PullRequests.EditComment(
-		context.Background(),
+		ctx,
 		*request.Repo.Owner.Login,
 		*request.Repo.Name,
 		*request.Comment.ID,
@@ -173,60 +236,93 @@ func (matchHandler *MatchHandler) HandleMatchesFromPullRequestReviewComment(
 	return nil
 }
 
+// redactContent redacts matches from an issue, pull request or comment. Honeytokens are never redacted silently: a
+// notice is added to the content, and the security team is alerted before the content is redacted. The content is
+// redacted even if the alert fails, in which case the notice asks whoever sees it to tell the security team.
+func (matchHandler *MatchHandler) redactContent(
+	ctx context.Context,
+	repoOwner string,
+	repoName string,
+	surface string,
+	url string,
+	content string,
+	matches []scanning.LineMatch) string {
+
+	newContent := redactMatchesFromContent(content, matches, '*')
+	if !scanning.HasHoneytokens(matches) {
+		return newContent
+	}
+
+	err := matchHandler.alertHoneytokens(ctx, repoOwner, repoName, surface, lineMatchSightings(url, matches))
+	if err != nil {
+		log.Printf("ALERT FAILED: %v\n", err)
+		return newContent + honeytokenRedactionAlertFailedNotice
+	}
+
+	return newContent + honeytokenRedactionNotice
+}
+
 func redactMatchesFromContent(content string, lineMatches []scanning.LineMatch, replacementCharacter rune) string {
 
-	contentRunes := []rune(content)
+	// Keep the line endings so that the content can be joined back together unchanged
+	lines := strings.SplitAfter(content, "\n")
 	for _, lineMatch := range lineMatches {
-		lineNumber := lineMatch.LineNumber
-
-		// Skip to the specified line number
-		currentLineNumber := 1
-		indexInLine := 0
-		for i, ch := range contentRunes {
-
-			// Counting each new line we find to find the line number
-			startOfNewLine := false
-			if ch == '\n' {
-				currentLineNumber++
-				indexInLine = 0
-				startOfNewLine = true
-			}
+		lineIndex := lineMatch.LineNumber - 1
+		if lineIndex < 0 || lineIndex >= len(lines) {
+			continue
+		}
 
-			// Make sure we're on the right line
-			if currentLineNumber == lineNumber {
+		lines[lineIndex] = redactColumns(lines[lineIndex], lineMatch.StartColumn, lineMatch.EndColumn, replacementCharacter)
+	}
 
-				// Check if the current index on the line is within the range of one of the matches
-				if indexInLine >= lineMatch.StartIndex && indexInLine < lineMatch.EndIndex {
-					contentRunes[i] = replacementCharacter
-				}
-			}
+	return strings.Join(lines, "")
+}
 
-			// Don't increment if we just decided we're on a new line
-			if !startOfNewLine {
-				indexInLine++
-			}
+// redactColumns replaces the runes in a line between the start and end columns. Line endings and any invalid UTF-8
+// bytes outside of the range are left as they were.
+func redactColumns(line string, startColumn int, endColumn int, replacementCharacter rune) string {
+	var builder strings.Builder
+	column := 0
+	for index := 0; index < len(line); {
+		ch, size := utf8.DecodeRuneInString(line[index:])
+		if column >= startColumn && column < endColumn && ch != '\r' && ch != '\n' {
+			builder.WriteRune(replacementCharacter)
+		} else {
+			builder.WriteString(line[index : index+size])
 		}
-	}
 
-	contentString := string(contentRunes)
+		index += size
+		column++
+	}
 
-	return contentString
+	return builder.String()
 }
 
 func BuildMessage(results []scanning.CommitScanResult) (string, string) {
 	var title string
 	var body string
 
-	if len(results) > 1 {
-		title = fmt.Sprintf("Potentially sensitive data found in %d commits.", len(results))
+	commitsWithMatches := 0
+	for _, result := range results {
+		if result.HasMatches() {
+			commitsWithMatches++
+		}
+	}
+
+	if commitsWithMatches > 1 {
+		title = fmt.Sprintf("Potentially sensitive data found in %d commits.", commitsWithMatches)
 	} else {
 		title = "Potentially sensitive data found in a commit."
 	}
 
-	if len(results) > 1 {
-		body = fmt.Sprintf("Potentially sensitive data has been found in %d commits.", len(results))
-	} else {
+	if commitsWithMatches > 1 {
+		body = fmt.Sprintf("Potentially sensitive data has been found in %d commits.", commitsWithMatches)
+	} else if commitsWithMatches == 1 {
 		body = "Potentially sensitive data has been found in a commit."
+	} else if scanning.AnyCommitIsIncomplete(results) {
+		body = "No potentially sensitive data has been found, but some files could not be scanned in full."
+	} else {
+		body = "No potentially sensitive data has been found."
 	}
 
 	body += "\n\n"
@@ -240,8 +336,33 @@ func BuildMessage(results []scanning.CommitScanResult) (string, string) {
 			for _, match := range result.Matches {
 
 				// Todo: Group lines which are directly below each other into one permalink (e.g. #L2-L4)
-				body += fmt.Sprintf("#### %s:\n", match.Kind)
-				body += fmt.Sprintf("`%s`\n", match.Path)
+				body += fmt.Sprintf("#### %s (%s severity):\n", match.Kind, match.Severity)
+				if match.Honeytoken != "" {
+					body += fmt.Sprintf("Honeytoken: %s\n", match.Honeytoken)
+				}
+				if match.Plugin != "" {
+					body += fmt.Sprintf("Found by plugin: %s\n", match.Plugin)
+				}
+				if match.Triage != nil {
+					body += fmt.Sprintf("Triage model: %s\n", match.Triage.Describe())
+				}
+				if len(match.SecondaryKinds) > 0 {
+					body += fmt.Sprintf("Also matched: %s\n", strings.Join(match.SecondaryKinds, ", "))
+				}
+				if match.Location != "" {
+					body += fmt.Sprintf("`%s` (%s)\n", match.Path, match.Location)
+				} else if match.CodeContext != "" {
+					body += fmt.Sprintf("`%s` (%s)\n", match.Path, match.CodeContext)
+				} else {
+					body += fmt.Sprintf("`%s`\n", match.Path)
+				}
+				body += fmt.Sprintf("Value: `%s` (%d characters)\n", match.Preview, match.Length)
+				if match.ContextPreview != "" {
+					body += fmt.Sprintf("Context: `%s`\n", match.ContextPreview)
+				}
+				if match.Remediation != "" {
+					body += fmt.Sprintf("Remediation: %s\n", match.Remediation)
+				}
 				body += fmt.Sprintf("%s#L%d\n", match.PermalinkURL, match.LineNumber)
 			}
 
@@ -249,5 +370,36 @@ func BuildMessage(results []scanning.CommitScanResult) (string, string) {
 		}
 	}
 
+	// List any files which ran out of scan budget so they can be reviewed manually
+	var incompleteBody string
+	for _, result := range results {
+		for _, incomplete := range result.Incomplete {
+			incompleteBody += fmt.Sprintf("`%s` in %s:\n", incomplete.Path, result.Commit)
+			for _, reason := range incomplete.Reasons {
+				incompleteBody += fmt.Sprintf("- %s\n", reason)
+			}
+		}
+	}
+
+	if len(incompleteBody) > 0 {
+		body += "#### Incomplete scans:\n"
+		body += "The following files could not be scanned in full and should be reviewed manually.\n"
+		body += incompleteBody
+	}
+
+	// List skipped files too, so that nothing is hidden
+	var skippedBody string
+	for _, result := range results {
+		for _, skipped := range result.Skipped {
+			skippedBody += fmt.Sprintf("- `%s` in %s: %s\n", skipped.Path, result.Commit, skipped.Reason)
+		}
+	}
+
+	if len(skippedBody) > 0 {
+		body += "#### Skipped files:\n"
+		body += "The following files were not scanned because they are vendored, generated or minified.\n"
+		body += skippedBody
+	}
+
 	return title, body
 }
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"Orca/pkg/scanning"
+	"context"
+	"testing"
+)
+
+func TestRedactMatchesFromContent(t *testing.T) {
+	scanner := &scanning.Scanner{
+		Patterns: []scanning.SearchPattern{
+			{Pattern: "secret[0-9]*", Kind: "Test Secret"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"ascii", "my secret123 here", "my ********* here"},
+		{"emoji before match", "🔑 secret1 😀", "🔑 ******* 😀"},
+		{"non-latin before match", "пароль: secret42", "пароль: ********"},
+		{"cjk on a later line", "first line\n秘密は secret7 です", "first line\n秘密は ******* です"},
+		{"crlf line endings", "a secret1\r\nb secret22\r\n", "a *******\r\nb ********\r\n"},
+		{"match at end of crlf line", "secret\r\n", "******\r\n"},
+		{"multiple matches on a line", "é secret1 é secret2", "é ******* é *******"},
+		{"combining characters", "é secret9", "é *******"},
+		{"invalid utf-8 before match", "\xff secret1 \xfe", "\xff ******* \xfe"},
+		{"no matches", "nothing to see here", "nothing to see here"},
+		{"empty content", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := scanner.CheckContent(context.Background(), test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := redactMatchesFromContent(test.content, matches, '*')
+			if actual != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
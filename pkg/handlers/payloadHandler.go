@@ -5,8 +5,10 @@ import (
 	"Orca/pkg/scanning"
 	"context"
 	"crypto/rsa"
+	"fmt"
 	"github.com/google/go-github/v33/github"
 	"log"
+	"time"
 )
 
 type PayloadHandler struct {
@@ -14,20 +16,25 @@ type PayloadHandler struct {
 	AppId          int
 	GitHubClient   *github.Client
 	Scanner        *scanning.Scanner
+	SecurityAlerts SecurityAlertOptions
+	AlertClients   AlertClientFactory
 }
 
 func NewPayloadHandler(
+	ctx context.Context,
 	installationId int64,
 	appId int,
 	privateKey *rsa.PrivateKey,
-	patternStore *scanning.PatternStore) (*PayloadHandler, error) {
+	patternStore *scanning.PatternStore,
+	scannerOptions scanning.ScannerOptions,
+	securityAlerts SecurityAlertOptions) (*PayloadHandler, error) {
 
-	scanner, err := scanning.NewScanner(patternStore)
+	scanner, err := scanning.NewScanner(patternStore, scannerOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	gitHubApiClient, err := api.GetGitHubApiClient(installationId, appId, privateKey)
+	gitHubApiClient, err := api.GetGitHubApiClient(ctx, installationId, appId, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -37,28 +44,32 @@ func NewPayloadHandler(
 		AppId:          appId,
 		GitHubClient:   gitHubApiClient,
 		Scanner:        scanner,
+		SecurityAlerts: securityAlerts,
+		AlertClients: func(ctx context.Context, owner string, name string) (*github.Client, error) {
+			return api.GetRepositoryInstallationClient(ctx, owner, name, appId, privateKey)
+		},
 	}
 
 	return &handler, nil
 }
 
-func (handler *PayloadHandler) HandleInstallation(installationPayload *github.InstallationEvent) {
+func (handler *PayloadHandler) HandleInstallation(ctx context.Context, installationPayload *github.InstallationEvent) {
 
 	// Todo: Scan the repository for any sensitive information
 	// 	May not be viable for large repositories with a long history
 }
 
-func (handler *PayloadHandler) HandlePush(pushPayload *github.PushEvent) {
+func (handler *PayloadHandler) HandlePush(ctx context.Context, pushPayload *github.PushEvent) {
 	log.Println("Handling push...")
 
 	// If any Pull Requests are open for ths branch, then ignore this and let the CI check handle it
 	pullRequests, _, err := handler.GitHubClient.PullRequests.List(
-		context.Background(),
+		ctx,
 		*pushPayload.Repo.Owner.Login,
 		*pushPayload.Repo.Name,
 		&github.PullRequestListOptions{
-			State:       "open",
-			Head:        fmt.Sprintf("%s:%s", *pushPayload.Pusher.Name, *pushPayload.Ref),
+			State: "open",
+			Head:  fmt.Sprintf("%s:%s", *pushPayload.Pusher.Name, *pushPayload.Ref),
 		})
 
 	if len(pullRequests) > 0 {
@@ -67,45 +78,57 @@ func (handler *PayloadHandler) HandlePush(pushPayload *github.PushEvent) {
 	}
 
 	// Check the commits
-	commitScanResults, err := handler.Scanner.CheckPush(pushPayload, handler.GitHubClient)
+	handler.applyRepositoryConfig(
+		ctx,
+		*pushPayload.Repo.Owner.Login,
+		*pushPayload.Repo.Name,
+		trustedConfigRef(pushPayload.GetRef(), pushPayload.Repo.GetDefaultBranch(), pushPayload.GetBefore()))
+	commitScanResults, err := handler.Scanner.CheckPush(ctx, pushPayload, handler.GitHubClient)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
-	if len(commitScanResults) > 0 {
+	if scanning.AnyCommitHasMatches(commitScanResults) {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
-		err := matchHandler.HandleMatchesFromPush(pushPayload, commitScanResults)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
+		err := matchHandler.HandleMatchesFromPush(recordCtx, pushPayload, commitScanResults)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
 		log.Println("Push has been addressed")
+	} else if len(commitScanResults) > 0 {
+		log.Println("No matches to address, but some files could not be scanned in full")
 	} else {
 		log.Println("No matches to address")
 	}
 }
 
-func (handler *PayloadHandler) HandleIssue(issuePayload *github.IssuesEvent) {
+func (handler *PayloadHandler) HandleIssue(ctx context.Context, issuePayload *github.IssuesEvent) {
 	log.Println("Handling issue...")
 
 	// Check the contents of the issue
-	issueScanResult, err := handler.Scanner.CheckIssue(issuePayload)
+	handler.applyRepositoryConfig(ctx, *issuePayload.Repo.Owner.Login, *issuePayload.Repo.Name, "")
+	issueScanResult, err := handler.Scanner.CheckIssue(ctx, issuePayload)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
 	if issueScanResult.HasMatches() {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
-		err := matchHandler.HandleMatchesFromIssue(issuePayload, issueScanResult)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
+		err := matchHandler.HandleMatchesFromIssue(recordCtx, issuePayload, issueScanResult)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
@@ -115,23 +138,28 @@ func (handler *PayloadHandler) HandleIssue(issuePayload *github.IssuesEvent) {
 	}
 }
 
-func (handler *PayloadHandler) HandleIssueComment(issueCommentPayload *github.IssueCommentEvent) {
+func (handler *PayloadHandler) HandleIssueComment(
+	ctx context.Context,
+	issueCommentPayload *github.IssueCommentEvent) {
 	log.Println("Handling issue...")
 
 	// Check the contents of the comment
-	issueScanResult, err := handler.Scanner.CheckIssueComment(issueCommentPayload)
+	handler.applyRepositoryConfig(ctx, *issueCommentPayload.Repo.Owner.Login, *issueCommentPayload.Repo.Name, "")
+	issueScanResult, err := handler.Scanner.CheckIssueComment(ctx, issueCommentPayload)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
 	if issueScanResult.HasMatches() {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
-		err := matchHandler.HandleMatchesFromIssueComment(issueCommentPayload, issueScanResult)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
+		err := matchHandler.HandleMatchesFromIssueComment(recordCtx, issueCommentPayload, issueScanResult)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
@@ -141,23 +169,26 @@ func (handler *PayloadHandler) HandleIssueComment(issueCommentPayload *github.Is
 	}
 }
 
-func (handler *PayloadHandler) HandlePullRequest(pullRequestPayload *github.PullRequestEvent) {
+func (handler *PayloadHandler) HandlePullRequest(ctx context.Context, pullRequestPayload *github.PullRequestEvent) {
 	log.Println("Handling pull request...")
 
 	// Check the contents of the pull request
-	pullRequestScanResult, err := handler.Scanner.CheckPullRequest(pullRequestPayload)
+	handler.applyRepositoryConfig(ctx, *pullRequestPayload.Repo.Owner.Login, *pullRequestPayload.Repo.Name, "")
+	pullRequestScanResult, err := handler.Scanner.CheckPullRequest(ctx, pullRequestPayload)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
 	if pullRequestScanResult.HasMatches() {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
-		err := matchHandler.HandleMatchesFromPullRequest(pullRequestPayload, pullRequestScanResult)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
+		err := matchHandler.HandleMatchesFromPullRequest(recordCtx, pullRequestPayload, pullRequestScanResult)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
@@ -167,23 +198,35 @@ func (handler *PayloadHandler) HandlePullRequest(pullRequestPayload *github.Pull
 	}
 }
 
-func (handler *PayloadHandler) HandlePullRequestReview(pullRequestReviewPayload *github.PullRequestReviewEvent) {
+func (handler *PayloadHandler) HandlePullRequestReview(
+	ctx context.Context,
+	pullRequestReviewPayload *github.PullRequestReviewEvent) {
 	log.Println("Handling pull request review...")
 
 	// Check the contents of the pull request review
-	pullRequestReviewScanResult, err := handler.Scanner.CheckPullRequestReview(pullRequestReviewPayload)
+	handler.applyRepositoryConfig(
+		ctx,
+		*pullRequestReviewPayload.Repo.Owner.Login,
+		*pullRequestReviewPayload.Repo.Name,
+		"")
+	pullRequestReviewScanResult, err := handler.Scanner.CheckPullRequestReview(ctx, pullRequestReviewPayload)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
 	if pullRequestReviewScanResult.HasMatches() {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
-		err := matchHandler.HandleMatchesFromPullRequestReview(pullRequestReviewPayload, pullRequestReviewScanResult)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
+		err := matchHandler.HandleMatchesFromPullRequestReview(
+			recordCtx,
+			pullRequestReviewPayload,
+			pullRequestReviewScanResult)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
@@ -194,25 +237,36 @@ func (handler *PayloadHandler) HandlePullRequestReview(pullRequestReviewPayload
 }
 
 func (handler *PayloadHandler) HandlePullRequestReviewComment(
+	ctx context.Context,
 	pullRequestReviewCommentPayload *github.PullRequestReviewCommentEvent) {
 	log.Println("Handling pull request review comment...")
 
 	// Check the contents of the pull request review
-	pullRequestReviewCommentScanResult, err := handler.Scanner.CheckPullRequestReviewComment(pullRequestReviewCommentPayload)
+	handler.applyRepositoryConfig(
+		ctx,
+		*pullRequestReviewCommentPayload.Repo.Owner.Login,
+		*pullRequestReviewCommentPayload.Repo.Name,
+		"")
+	pullRequestReviewCommentScanResult, err := handler.Scanner.CheckPullRequestReviewComment(
+		ctx,
+		pullRequestReviewCommentPayload)
 	if err != nil {
-		log.Fatal(err)
+		handleError(ctx, err)
 		return
 	}
 
 	// If anything shows up in the results, take action
 	if pullRequestReviewCommentScanResult.HasMatches() {
 		log.Println("Potentially sensitive information detected. Rectifying...")
-		matchHandler := NewMatchHandler(handler.GitHubClient)
+		matchHandler := NewMatchHandler(handler.GitHubClient, handler.SecurityAlerts, handler.AlertClients)
+		recordCtx, cancel := recordingContext()
+		defer cancel()
 		err := matchHandler.HandleMatchesFromPullRequestReviewComment(
+			recordCtx,
 			pullRequestReviewCommentPayload,
 			pullRequestReviewCommentScanResult)
 		if err != nil {
-			log.Fatal(err)
+			handleError(recordCtx, err)
 			return
 		}
 
@@ -221,3 +275,24 @@ func (handler *PayloadHandler) HandlePullRequestReviewComment(
 		log.Println("No matches to address")
 	}
 }
+
+// recordingTimeout is how long recording what was found in an event may take, such as opening an issue, redacting
+// content or alerting on honeytokens
+const recordingTimeout = 30 * time.Second
+
+// recordingContext returns the context for recording what was found in an event. It isn't derived from the event's
+// context, so that what was found before the event was cancelled or ran out of time is still recorded.
+func recordingContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), recordingTimeout)
+}
+
+// handleError stops Orca on an unexpected error. If the event was cancelled or ran out of time, the error is only
+// logged, as the next event may still be handled.
+func handleError(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		log.Printf("Stopped handling event: %v\n", err)
+		return
+	}
+
+	log.Fatal(err)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"Orca/pkg/scanning"
+	"context"
+	"encoding/base64"
+	"github.com/google/go-github/v33/github"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// applyRepositoryConfig loads the repository's own scanning settings at a ref, or its default branch if the ref is
+// empty, and applies them to the scanner. The ref should never be a commit that is about to be scanned, so that a
+// change can't switch off the scan of itself. A missing or invalid config leaves the scanner's settings as they are.
+func (handler *PayloadHandler) applyRepositoryConfig(
+	ctx context.Context,
+	repoOwner string,
+	repoName string,
+	ref string) {
+	content, _, response, err := handler.GitHubClient.Repositories.GetContents(
+		ctx,
+		repoOwner,
+		repoName,
+		scanning.RepositoryConfigPath,
+		&github.RepositoryContentGetOptions{
+			Ref: ref,
+		})
+	if err != nil {
+		if response == nil || response.StatusCode != http.StatusNotFound {
+			log.Printf("Could not fetch %s, using the default settings: %v\n", scanning.RepositoryConfigPath, err)
+		}
+		return
+	}
+
+	if content == nil || content.Content == nil {
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(*content.Content)
+	if err != nil {
+		log.Printf("Could not decode %s, using the default settings: %v\n", scanning.RepositoryConfigPath, err)
+		return
+	}
+
+	config, err := scanning.ParseRepositoryConfig(data)
+	if err != nil {
+		log.Printf("%v, using the default settings\n", err)
+		return
+	}
+
+	handler.Scanner.ApplyRepositoryConfig(config)
+}
+
+// trustedConfigRef returns the ref to load a repository's settings from when scanning commits pushed to a branch.
+// Settings are read from the default branch rather than the pushed commits, and from before the push when the push
+// is to the default branch itself.
+func trustedConfigRef(ref string, defaultBranch string, before string) string {
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	if defaultBranch == "" || branch != defaultBranch || strings.Trim(before, "0") == "" {
+		return ""
+	}
+
+	return before
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import "testing"
+
+func TestTrustedConfigRef(t *testing.T) {
+	tests := []struct {
+		name          string
+		ref           string
+		defaultBranch string
+		before        string
+		expected      string
+	}{
+		{
+			name:          "push to another branch",
+			ref:           "refs/heads/feature",
+			defaultBranch: "main",
+			before:        "a1b2c3",
+			expected:      "",
+		},
+		{
+			name:          "push to the default branch",
+			ref:           "refs/heads/main",
+			defaultBranch: "main",
+			before:        "a1b2c3",
+			expected:      "a1b2c3",
+		},
+		{
+			name:          "check suite on the default branch",
+			ref:           "main",
+			defaultBranch: "main",
+			before:        "a1b2c3",
+			expected:      "a1b2c3",
+		},
+		{
+			name:          "default branch created by the push",
+			ref:           "refs/heads/main",
+			defaultBranch: "main",
+			before:        "0000000000000000000000000000000000000000",
+			expected:      "",
+		},
+		{
+			name:          "unknown default branch",
+			ref:           "refs/heads/main",
+			defaultBranch: "",
+			before:        "a1b2c3",
+			expected:      "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ref := trustedConfigRef(test.ref, test.defaultBranch, test.before); ref != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, ref)
+			}
+		})
+	}
+}
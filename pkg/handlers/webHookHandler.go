@@ -2,32 +2,51 @@ package handlers
 
 import (
 	"Orca/pkg/scanning"
+	"context"
 	"crypto/rsa"
 	"github.com/google/go-github/v33/github"
 	"log"
 	"net/http"
+	"time"
 )
 
 type WebhookHandler struct {
-	Path         string
-	AppId        int
-	PatternStore *scanning.PatternStore
-	privateKey   *rsa.PrivateKey
-	secret       string
+	Path           string
+	AppId          int
+	PatternStore   *scanning.PatternStore
+	ScannerOptions scanning.ScannerOptions
+	SecurityAlerts SecurityAlertOptions
+
+	// EventTimeout is the longest time handling a single event may take, including scanning and calling GitHub. 0
+	// disables the limit.
+	EventTimeout time.Duration
+
+	// ctx is cancelled once Orca has shut down, which stops any events still being handled
+	ctx        context.Context
+	privateKey *rsa.PrivateKey
+	secret     string
 }
 
 func NewWebhookHandler(
+	ctx context.Context,
 	webHookPath string,
 	appId int,
 	patternStore *scanning.PatternStore,
+	scannerOptions scanning.ScannerOptions,
+	securityAlerts SecurityAlertOptions,
+	eventTimeout time.Duration,
 	privateKey *rsa.PrivateKey,
 	gitHubSecret string) *WebhookHandler {
 	handler := WebhookHandler{
-		Path:         webHookPath,
-		AppId:        appId,
-		PatternStore: patternStore,
-		privateKey:   privateKey,
-		secret:       gitHubSecret,
+		Path:           webHookPath,
+		AppId:          appId,
+		PatternStore:   patternStore,
+		ScannerOptions: scannerOptions,
+		SecurityAlerts: securityAlerts,
+		EventTimeout:   eventTimeout,
+		ctx:            ctx,
+		privateKey:     privateKey,
+		secret:         gitHubSecret,
 	}
 
 	return &handler
@@ -35,8 +54,16 @@ func NewWebhookHandler(
 
 func (webHookHandler *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	err := webHookHandler.handleWebHookRequest(r)
-	if err != nil {
+	// The request's own context isn't used, as it is cancelled if GitHub stops waiting for the response
+	ctx, cancel := webHookHandler.eventContext()
+	defer cancel()
+
+	err := webHookHandler.handleWebHookRequest(ctx, r)
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Stopped handling payload: %v\n", err)
+		http.Error(w, "stopped handling payload", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
 		http.Error(w, "failed to handle payload", http.StatusBadRequest)
 		log.Fatalf("failed to handle payload: %v", err)
 	}
@@ -44,7 +71,17 @@ func (webHookHandler *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.R
 	w.WriteHeader(http.StatusOK)
 }
 
-func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) error {
+// eventContext returns the context for handling a single event, which is cancelled once the event times out or Orca
+// shuts down
+func (webHookHandler *WebhookHandler) eventContext() (context.Context, context.CancelFunc) {
+	if webHookHandler.EventTimeout <= 0 {
+		return context.WithCancel(webHookHandler.ctx)
+	}
+
+	return context.WithTimeout(webHookHandler.ctx, webHookHandler.EventTimeout)
+}
+
+func (webHookHandler *WebhookHandler) handleWebHookRequest(ctx context.Context, r *http.Request) error {
 
 	// Gets the body as bytes and validates the signature
 	body, err := github.ValidatePayload(r, []byte(webHookHandler.secret))
@@ -62,20 +99,20 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 
 	switch payload := parsedPayload.(type) {
 	case *github.InstallationEvent:
-		payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+		payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 		if err != nil {
 			return err
 		}
 
-		payloadHandler.HandleInstallation(payload)
+		payloadHandler.HandleInstallation(ctx, payload)
 
 	case *github.PushEvent:
-		payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+		payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 		if err != nil {
 			return err
 		}
 
-		payloadHandler.HandlePush(payload)
+		payloadHandler.HandlePush(ctx, payload)
 
 	case *github.IssuesEvent:
 		if *payload.Sender.Type == "Bot" {
@@ -83,12 +120,12 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 		}
 
 		if *payload.Action == "opened" || *payload.Action == "edited" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandleIssue(payload)
+			payloadHandler.HandleIssue(ctx, payload)
 		}
 
 	case *github.IssueCommentEvent:
@@ -97,12 +134,12 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 		}
 
 		if *payload.Action == "created" || *payload.Action == "edited" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandleIssueComment(payload)
+			payloadHandler.HandleIssueComment(ctx, payload)
 		}
 
 	case *github.PullRequestEvent:
@@ -111,12 +148,12 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 		}
 
 		if *payload.Action == "opened" || *payload.Action == "edited" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandlePullRequest(payload)
+			payloadHandler.HandlePullRequest(ctx, payload)
 		}
 
 	case *github.PullRequestReviewEvent:
@@ -125,12 +162,12 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 		}
 
 		if *payload.Action == "submitted" || *payload.Action == "edited" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandlePullRequestReview(payload)
+			payloadHandler.HandlePullRequestReview(ctx, payload)
 		}
 
 	case *github.PullRequestReviewCommentEvent:
@@ -139,30 +176,39 @@ func (webHookHandler *WebhookHandler) handleWebHookRequest(r *http.Request) erro
 		}
 
 		if *payload.Action == "created" || *payload.Action == "edited" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandlePullRequestReviewComment(payload)
+			payloadHandler.HandlePullRequestReviewComment(ctx, payload)
 		}
 
 	case *github.CheckSuiteEvent:
 		if *payload.Action == "requested" || *payload.Action == "rerequested" {
-			payloadHandler, err := webHookHandler.MakePayloadHandler(payload.Installation.ID)
+			payloadHandler, err := webHookHandler.MakePayloadHandler(ctx, payload.Installation.ID)
 			if err != nil {
 				return err
 			}
 
-			payloadHandler.HandleCheckSuite(payload)
+			payloadHandler.HandleCheckSuite(ctx, payload)
 		}
 	}
 
 	return nil
 }
 
-func (webHookHandler *WebhookHandler) MakePayloadHandler(installationId *int64) (*PayloadHandler, error) {
-	payloadHandler, err := NewPayloadHandler(*installationId, webHookHandler.AppId, webHookHandler.privateKey, webHookHandler.PatternStore)
+func (webHookHandler *WebhookHandler) MakePayloadHandler(
+	ctx context.Context,
+	installationId *int64) (*PayloadHandler, error) {
+	payloadHandler, err := NewPayloadHandler(
+		ctx,
+		*installationId,
+		webHookHandler.AppId,
+		webHookHandler.privateKey,
+		webHookHandler.PatternStore,
+		webHookHandler.ScannerOptions,
+		webHookHandler.SecurityAlerts)
 	if err != nil {
 		return nil, err
 	}
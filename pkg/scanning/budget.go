@@ -0,0 +1,190 @@
+package scanning
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ScanBudget limits how much time and content a single scan may consume. A zero value for any limit means that
+// limit is not enforced.
+type ScanBudget struct {
+	MaxFileDuration    time.Duration
+	MaxPatternDuration time.Duration
+	MaxFileBytes       int
+	MaxPatternBytes    int
+
+	// Lines longer than MaxLineLength are scanned in windows of MaxLineLength bytes, with each window overlapping the
+	// previous one by WindowOverlap bytes so that matches spanning a window boundary are still found
+	MaxLineLength int
+	WindowOverlap int
+}
+
+func DefaultScanBudget() ScanBudget {
+	return ScanBudget{
+		MaxFileDuration:    30 * time.Second,
+		MaxPatternDuration: 10 * time.Second,
+		MaxFileBytes:       10 * 1024 * 1024,
+		MaxPatternBytes:    0,
+		MaxLineLength:      4096,
+		WindowOverlap:      256,
+	}
+}
+
+// scanState tracks the budget consumed while scanning a single piece of content. A scan also stops early if its
+// context is cancelled or reaches its deadline.
+type scanState struct {
+	ctx              context.Context
+	budget           ScanBudget
+	started          time.Time
+	bytesScanned     int
+	patternDurations []time.Duration
+	patternBytes     []int
+	patternReasons   []string
+	fileReason       string
+}
+
+func newScanState(ctx context.Context, budget ScanBudget, patternCount int) *scanState {
+	return &scanState{
+		ctx:              ctx,
+		budget:           budget,
+		started:          time.Now(),
+		patternDurations: make([]time.Duration, patternCount),
+		patternBytes:     make([]int, patternCount),
+		patternReasons:   make([]string, patternCount),
+	}
+}
+
+// consumeLine accounts for a line about to be scanned, returning false if the file budget has been exhausted
+func (state *scanState) consumeLine(line string, lineNumber int) bool {
+	if state.fileReason != "" {
+		return false
+	}
+
+	if state.budget.MaxFileBytes > 0 && state.bytesScanned+len(line) > state.budget.MaxFileBytes {
+		state.fileReason = fmt.Sprintf(
+			"scan byte budget of %d bytes exceeded, stopped at line %d",
+			state.budget.MaxFileBytes,
+			lineNumber)
+		return false
+	}
+
+	if !state.checkFileDuration(lineNumber) {
+		return false
+	}
+
+	// Include the new line character that was split on
+	state.bytesScanned += len(line) + 1
+	return true
+}
+
+// checkFileDuration returns false if the time budget for the whole file has been exhausted, or the scan was cancelled
+func (state *scanState) checkFileDuration(lineNumber int) bool {
+	if state.fileReason != "" {
+		return false
+	}
+
+	if err := state.ctx.Err(); err != nil {
+		state.fileReason = fmt.Sprintf("scan cancelled at line %d: %v", lineNumber, err)
+		return false
+	}
+
+	if state.budget.MaxFileDuration > 0 && time.Since(state.started) > state.budget.MaxFileDuration {
+		state.fileReason = fmt.Sprintf(
+			"scan time budget of %s exceeded, stopped at line %d",
+			state.budget.MaxFileDuration,
+			lineNumber)
+		return false
+	}
+
+	return true
+}
+
+func (state *scanState) patternExhausted(patternIndex int) bool {
+	return state.patternReasons[patternIndex] != ""
+}
+
+// recordPattern accounts for a pattern having scanned a number of bytes, marking the pattern as exhausted if it has
+// gone over its budget
+func (state *scanState) recordPattern(patternIndex int, pattern SearchPattern, bytes int, duration time.Duration, lineNumber int) {
+	state.patternBytes[patternIndex] += bytes
+	state.patternDurations[patternIndex] += duration
+
+	if state.budget.MaxPatternDuration > 0 && state.patternDurations[patternIndex] > state.budget.MaxPatternDuration {
+		state.patternReasons[patternIndex] = fmt.Sprintf(
+			"pattern \"%s\" exceeded its time budget of %s at line %d",
+			pattern.Kind,
+			state.budget.MaxPatternDuration,
+			lineNumber)
+	} else if state.budget.MaxPatternBytes > 0 && state.patternBytes[patternIndex] > state.budget.MaxPatternBytes {
+		state.patternReasons[patternIndex] = fmt.Sprintf(
+			"pattern \"%s\" exceeded its byte budget of %d bytes at line %d",
+			pattern.Kind,
+			state.budget.MaxPatternBytes,
+			lineNumber)
+	}
+}
+
+// reasons returns why the scan was incomplete, if it was
+func (state *scanState) reasons() []string {
+	var result []string
+	if state.fileReason != "" {
+		result = append(result, state.fileReason)
+	}
+
+	for _, reason := range state.patternReasons {
+		if reason != "" {
+			result = append(result, reason)
+		}
+	}
+
+	return result
+}
+
+type lineWindow struct {
+	start int
+	end   int
+}
+
+// lineWindows splits a line into overlapping windows no longer than maxLength bytes. Window boundaries are moved back
+// to the start of a UTF-8 sequence so that no character is split between windows.
+func lineWindows(line string, maxLength int, overlap int) []lineWindow {
+	if maxLength <= 0 || len(line) <= maxLength {
+		return []lineWindow{{start: 0, end: len(line)}}
+	}
+
+	if overlap < 0 || overlap >= maxLength {
+		overlap = 0
+	}
+
+	var windows []lineWindow
+	start := 0
+	for {
+		end := start + maxLength
+		if end >= len(line) {
+			windows = append(windows, lineWindow{start: start, end: len(line)})
+			return windows
+		}
+
+		if boundary := runeBoundary(line, end); boundary > start {
+			end = boundary
+		}
+		windows = append(windows, lineWindow{start: start, end: end})
+
+		next := runeBoundary(line, end-overlap)
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
+
+// runeBoundary moves an index back to the start of the UTF-8 sequence it falls within
+func runeBoundary(line string, index int) int {
+	for index > 0 && index < len(line) && !utf8.RuneStart(line[index]) {
+		index--
+	}
+
+	return index
+}
@@ -0,0 +1,191 @@
+package scanning
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineWindows(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		maxLength int
+		overlap   int
+		expected  []lineWindow
+	}{
+		{
+			name:      "short line",
+			line:      "0123",
+			maxLength: 8,
+			overlap:   2,
+			expected:  []lineWindow{{start: 0, end: 4}},
+		},
+		{
+			name:      "no maximum length",
+			line:      "0123456789",
+			maxLength: 0,
+			overlap:   2,
+			expected:  []lineWindow{{start: 0, end: 10}},
+		},
+		{
+			name:      "overlapping windows",
+			line:      "0123456789",
+			maxLength: 4,
+			overlap:   1,
+			expected:  []lineWindow{{start: 0, end: 4}, {start: 3, end: 7}, {start: 6, end: 10}},
+		},
+		{
+			name:      "overlap as long as a window",
+			line:      "0123456789",
+			maxLength: 4,
+			overlap:   4,
+			expected:  []lineWindow{{start: 0, end: 4}, {start: 4, end: 8}, {start: 8, end: 10}},
+		},
+		{
+			name:      "multibyte characters",
+			line:      "ééééé",
+			maxLength: 3,
+			overlap:   1,
+			expected: []lineWindow{
+				{start: 0, end: 2},
+				{start: 2, end: 4},
+				{start: 4, end: 6},
+				{start: 6, end: 8},
+				{start: 8, end: 10},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			windows := lineWindows(test.line, test.maxLength, test.overlap)
+			if !reflect.DeepEqual(windows, test.expected) {
+				t.Errorf("expected %v but got %v", test.expected, windows)
+			}
+		})
+	}
+}
+
+func TestWindowedMatches(t *testing.T) {
+	token := func(length int) string {
+		return "tok_" + strings.Repeat("a1", length)[:length-4]
+	}
+
+	tests := []struct {
+		name     string
+		line     string
+		expected [][2]int
+	}{
+		{
+			name:     "match cut off by the end of a window",
+			line:     strings.Repeat("x", 29) + " " + token(64) + " " + strings.Repeat("x", 33),
+			expected: [][2]int{{30, 94}},
+		},
+		{
+			name:     "match within the overlap",
+			line:     strings.Repeat("x", 49) + " " + token(12) + " " + strings.Repeat("x", 17),
+			expected: [][2]int{{50, 62}},
+		},
+		{
+			name:     "match found in the overlap and beyond it",
+			line:     strings.Repeat("x", 49) + " " + token(24) + " " + strings.Repeat("x", 5),
+			expected: [][2]int{{50, 74}},
+		},
+		{
+			name:     "matches in separate windows",
+			line:     token(24) + " " + strings.Repeat("x", 50) + " " + token(24),
+			expected: [][2]int{{0, 24}, {76, 100}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{
+				Patterns:  []SearchPattern{{Pattern: `tok_[a-z0-9]{8,}`, Kind: "Token"}},
+				Budget:    ScanBudget{MaxLineLength: 64, WindowOverlap: 16},
+				Detectors: DetectorOptions{Disabled: true},
+			}
+			result, err := scanner.ScanContent(test.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var spans [][2]int
+			for _, match := range result.Matches {
+				spans = append(spans, [2]int{match.StartIndex, match.EndIndex})
+			}
+			if !reflect.DeepEqual(spans, test.expected) {
+				t.Errorf("expected %v but got %v", test.expected, spans)
+			}
+		})
+	}
+}
+
+func TestScanBudgets(t *testing.T) {
+	content := strings.Repeat("tok_12345678 and some other text\n", 20)
+
+	tests := []struct {
+		name     string
+		budget   ScanBudget
+		matches  int
+		expected string
+	}{
+		{
+			name:    "within budget",
+			budget:  DefaultScanBudget(),
+			matches: 20,
+		},
+		{
+			name:     "file byte budget",
+			budget:   ScanBudget{MaxFileBytes: 100},
+			matches:  3,
+			expected: "scan byte budget of 100 bytes exceeded, stopped at line 4",
+		},
+		{
+			name:     "pattern byte budget",
+			budget:   ScanBudget{MaxPatternBytes: 64},
+			matches:  3,
+			expected: `pattern "Token" exceeded its byte budget of 64 bytes at line 3`,
+		},
+		{
+			name:     "file time budget",
+			budget:   ScanBudget{MaxFileDuration: time.Nanosecond},
+			expected: "scan time budget of 1ns exceeded, stopped at line ",
+		},
+		{
+			name:     "pattern time budget",
+			budget:   ScanBudget{MaxPatternDuration: time.Nanosecond},
+			matches:  1,
+			expected: `pattern "Token" exceeded its time budget of 1ns at line 1`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{
+				Patterns:  []SearchPattern{{Pattern: `tok_[0-9]+`, Kind: "Token"}},
+				Budget:    test.budget,
+				Detectors: DetectorOptions{Disabled: true},
+			}
+			result, err := scanner.ScanContent(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.expected == "" {
+				if result.IsIncomplete() {
+					t.Errorf("expected a complete scan but got %v", result.IncompleteReasons)
+				}
+			} else if len(result.IncompleteReasons) != 1 || !strings.HasPrefix(result.IncompleteReasons[0], test.expected) {
+				t.Errorf("expected %q but got %v", test.expected, result.IncompleteReasons)
+			}
+
+			// The time budget for the whole file may run out before or after the first line is scanned
+			if test.budget.MaxFileDuration == 0 && len(result.Matches) != test.matches {
+				t.Errorf("expected %d matches but got %d", test.matches, len(result.Matches))
+			}
+		})
+	}
+}
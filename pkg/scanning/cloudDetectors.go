@@ -0,0 +1,199 @@
+package scanning
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// awsKeyPairDistance is how many lines apart an AWS access key ID and secret access key can be to be reported as a pair
+const awsKeyPairDistance = 5
+
+var (
+	awsAccessKeyIdRegex     = regexp.MustCompile(`\b(?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16}\b`)
+	awsSecretAccessKeyRegex = regexp.MustCompile(`(?:^|[^A-Za-z0-9/+=])([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+=]|$)`)
+)
+
+var awsAccessKeyPairDetector = builtInDetector{
+	name:     "aws-access-key-pair",
+	severity: SeverityCritical,
+	remediation: "Deactivate the access key in IAM, create a new key for anything which uses it, and check CloudTrail " +
+		"for any use of the old key.",
+	detect: func(input *detectorInput) []detection {
+		var ids []detection
+		var secrets []detection
+		for i, line := range input.lines {
+			for _, span := range awsAccessKeyIdRegex.FindAllStringIndex(line, -1) {
+				ids = append(ids, newDetection(i, span[0], span[1], "AWS access key ID"))
+			}
+
+			for _, match := range awsSecretAccessKeyRegex.FindAllStringSubmatchIndex(line, -1) {
+				value := line[match[2]:match[3]]
+				classes := []string{CharacterClassLower, CharacterClassUpper, CharacterClassDigit}
+				if containsCharacterClasses(value, classes) && shannonEntropy(value) >= 4 {
+					secrets = append(secrets, newDetection(i, match[2], match[3], "AWS secret access key"))
+				}
+			}
+		}
+
+		// Neither value is reported alone, an ID is not a secret and 40 random characters could be anything
+		var result []detection
+		paired := map[detection]bool{}
+		for _, id := range ids {
+			for _, secret := range secrets {
+				if secret.lineIndex-id.lineIndex > awsKeyPairDistance || id.lineIndex-secret.lineIndex > awsKeyPairDistance {
+					continue
+				}
+
+				for _, found := range []detection{id, secret} {
+					if !paired[found] {
+						paired[found] = true
+						result = append(result, found)
+					}
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+var azureStorageKeyRegex = regexp.MustCompile(
+	`(?i)(?:AccountKey\s*=\s*|(?:storage|account)[\w.-]*key["']?\s*[:=]\s*["']?)([A-Za-z0-9+/]{86}==)`)
+
+var azureStorageKeyDetector = builtInDetector{
+	name:     "azure-storage-key",
+	severity: SeverityCritical,
+	remediation: "Rotate the storage account key in the Azure portal or with \"az storage account keys renew\", and " +
+		"use a managed identity or Key Vault instead of the connection string.",
+	detect: func(input *detectorInput) []detection {
+		var result []detection
+		for i, line := range input.lines {
+			for _, match := range azureStorageKeyRegex.FindAllStringSubmatchIndex(line, -1) {
+
+				// Storage account keys are 512 bits
+				key, err := base64.StdEncoding.Strict().DecodeString(line[match[2]:match[3]])
+				if err == nil && len(key) == 64 {
+					result = append(result, newDetection(i, match[2], match[3], "Azure storage account key"))
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+var azureSasSignatureRegex = regexp.MustCompile(`(?:^|[?&;\s"'])sig=([^&;\s"'#<>]+)`)
+
+var azureSasTokenDetector = builtInDetector{
+	name:     "azure-sas-token",
+	severity: SeverityHigh,
+	remediation: "Revoke the SAS token by removing its stored access policy or rotating the storage account key it " +
+		"was signed with, and issue short-lived tokens when they are needed instead of committing them.",
+	detect: func(input *detectorInput) []detection {
+		var result []detection
+		for i, line := range input.lines {
+			for _, match := range azureSasSignatureRegex.FindAllStringSubmatchIndex(line, -1) {
+				if isAzureSasToken(line, match[2], match[3]) {
+					result = append(result, newDetection(i, match[2], match[3], "Azure SAS token"))
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+// isAzureSasToken checks if the signature at a position in a line belongs to a SAS token, by parsing the query string
+// around it and checking for the parameters every SAS token has
+func isAzureSasToken(line string, signatureStart int, signatureEnd int) bool {
+	queryStart := strings.LastIndexAny(line[:signatureStart], "?;\t \"'") + 1
+	queryEnd := len(line)
+	if index := strings.IndexAny(line[signatureEnd:], ";\t \"'#<>"); index >= 0 {
+		queryEnd = signatureEnd + index
+	}
+
+	query, err := url.ParseQuery(strings.TrimPrefix(line[queryStart:queryEnd], "SharedAccessSignature="))
+	if err != nil || query.Get("sv") == "" || (query.Get("se") == "" && query.Get("sp") == "") {
+		return false
+	}
+
+	// Signatures are HMAC-SHA256 hashes
+	signature, err := base64.StdEncoding.DecodeString(query.Get("sig"))
+
+	return err == nil && len(signature) == 32
+}
+
+var azureClientSecretRegex = regexp.MustCompile(
+	`(?:^|[^A-Za-z0-9_~.-])([A-Za-z0-9_~.-]{3}[0-9]Q~[A-Za-z0-9_~.-]{31,34})(?:[^A-Za-z0-9_~.-]|$)`)
+
+var azureClientSecretDetector = builtInDetector{
+	name:     "azure-ad-client-secret",
+	severity: SeverityCritical,
+	remediation: "Delete the client secret from the app registration in Microsoft Entra ID, create a new one for " +
+		"anything which uses it, and store it in Key Vault.",
+	detect: func(input *detectorInput) []detection {
+		var result []detection
+		for i, line := range input.lines {
+			for _, match := range azureClientSecretRegex.FindAllStringSubmatchIndex(line, -1) {
+				if containsCharacterClasses(line[match[2]:match[3]], []string{CharacterClassLower, CharacterClassUpper}) {
+					result = append(result, newDetection(i, match[2], match[3], "Azure AD client secret"))
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+var gcpPrivateKeyRegex = regexp.MustCompile(`"private_key"\s*:\s*"([^"]+)"`)
+
+var gcpServiceAccountDetector = builtInDetector{
+	name:     "gcp-service-account-key",
+	severity: SeverityCritical,
+	remediation: "Delete the key from the service account in the Google Cloud console or with \"gcloud iam " +
+		"service-accounts keys delete\", and use workload identity instead of key files where possible.",
+	detect: func(input *detectorInput) []detection {
+		isServiceAccount := false
+		for _, line := range input.lines {
+			if strings.Contains(line, `"service_account"`) {
+				isServiceAccount = true
+				break
+			}
+		}
+
+		if !isServiceAccount {
+			return nil
+		}
+
+		var key struct {
+			Type       string `json:"type"`
+			PrivateKey string `json:"private_key"`
+		}
+		err := json.Unmarshal([]byte(strings.Join(input.lines, "\n")), &key)
+		if err != nil || key.Type != "service_account" {
+			return nil
+		}
+
+		block, _ := pem.Decode([]byte(key.PrivateKey))
+		if block == nil || block.Type != "PRIVATE KEY" {
+			return nil
+		}
+
+		if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return nil
+		}
+
+		for i, line := range input.lines {
+			if match := gcpPrivateKeyRegex.FindStringSubmatchIndex(line); match != nil {
+				return []detection{newDetection(i, match[2], match[3], "GCP service account key")}
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,160 @@
+package scanning
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character classes which can be required in a matched value
+const (
+	CharacterClassLower  = "lower"
+	CharacterClassUpper  = "upper"
+	CharacterClassDigit  = "digit"
+	CharacterClassSymbol = "symbol"
+)
+
+var characterClasses = map[string]func(rune) bool{
+	CharacterClassLower: unicode.IsLower,
+	CharacterClassUpper: unicode.IsUpper,
+	CharacterClassDigit: unicode.IsDigit,
+	CharacterClassSymbol: func(ch rune) bool {
+		return unicode.IsPunct(ch) || unicode.IsSymbol(ch)
+	},
+}
+
+// validateConstraints checks the globs and character classes of a pattern
+func (pattern *SearchPattern) validateConstraints() error {
+	for _, glob := range append(append([]string{}, pattern.Paths...), pattern.ExcludePaths...) {
+		if _, err := compileGlob(glob); err != nil {
+			return fmt.Errorf("invalid path \"%s\" in pattern \"%s\": %v", glob, pattern.Kind, err)
+		}
+	}
+
+	for _, class := range pattern.CharacterClasses {
+		if _, ok := characterClasses[class]; !ok {
+			return fmt.Errorf("invalid character class \"%s\" in pattern \"%s\"", class, pattern.Kind)
+		}
+	}
+
+	if pattern.MaxLength > 0 && pattern.MaxLength < pattern.MinLength {
+		return fmt.Errorf("maximum length is less than the minimum length in pattern \"%s\"", pattern.Kind)
+	}
+
+	return nil
+}
+
+// AppliesToPath checks if a pattern should be used for a file. Content which isn't from a file, such as an issue, has
+// no path, and is only checked by patterns which aren't restricted to certain paths or extensions.
+func (pattern *SearchPattern) AppliesToPath(filePath string) bool {
+	if len(filePath) == 0 {
+		return len(pattern.Paths) == 0 && len(pattern.Extensions) == 0
+	}
+
+	if len(pattern.Paths) > 0 && !matchesAnyGlob(pattern.Paths, filePath) {
+		return false
+	}
+
+	if matchesAnyGlob(pattern.ExcludePaths, filePath) {
+		return false
+	}
+
+	if len(pattern.Extensions) == 0 {
+		return true
+	}
+
+	fileName := strings.ToLower(path.Base(filePath))
+	for _, extension := range pattern.Extensions {
+		if strings.HasSuffix(fileName, "."+strings.TrimPrefix(strings.ToLower(extension), ".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsValue checks a matched value against the pattern's length and character class constraints
+func (pattern *SearchPattern) acceptsValue(value string) bool {
+	length := utf8.RuneCountInString(value)
+	if length < pattern.MinLength || (pattern.MaxLength > 0 && length > pattern.MaxLength) {
+		return false
+	}
+
+	return containsCharacterClasses(value, pattern.CharacterClasses)
+}
+
+// containsCharacterClasses checks that a value has at least one character from each of the classes
+func containsCharacterClasses(value string, classes []string) bool {
+	for _, class := range classes {
+		isInClass, ok := characterClasses[class]
+		if !ok || strings.IndexFunc(value, isInClass) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchesAnyGlob(globs []string, filePath string) bool {
+	for _, glob := range globs {
+		regex, err := compileGlob(glob)
+		if err != nil {
+			continue
+		}
+
+		// Globs without a slash match the file name in any directory, like in .gitignore
+		if !strings.Contains(glob, "/") {
+			if regex.MatchString(path.Base(filePath)) {
+				return true
+			}
+		} else if regex.MatchString(strings.TrimPrefix(filePath, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileGlob converts a glob into a regex. * and ? don't match slashes, ** matches any number of directories, and
+// [...] matches a character class.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	glob = strings.TrimPrefix(glob, "/")
+
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				builder.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				builder.WriteString(".*")
+				i++
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	builder.WriteString("$")
+
+	return regexp.Compile(builder.String())
+}
@@ -0,0 +1,72 @@
+package scanning
+
+import "testing"
+
+func TestAppliesToPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern SearchPattern
+		path    string
+		applies bool
+	}{
+		{"no constraints", SearchPattern{}, "docs/readme.md", true},
+		{"no constraints without path", SearchPattern{}, "", true},
+		{"file name glob", SearchPattern{Paths: []string{"appsettings.*.json"}}, "src/appsettings.Production.json", true},
+		{"file name glob mismatch", SearchPattern{Paths: []string{"appsettings.*.json"}}, "src/settings.json", false},
+		{"double star", SearchPattern{Paths: []string{"deploy/**/*.yaml"}}, "deploy/prod/eu/app.yaml", true},
+		{"double star root", SearchPattern{Paths: []string{"deploy/**/*.yaml"}}, "deploy/app.yaml", true},
+		{"single star stops at slash", SearchPattern{Paths: []string{"deploy/*.yaml"}}, "deploy/prod/app.yaml", false},
+		{"excluded", SearchPattern{ExcludePaths: []string{"docs/**"}}, "docs/setup/network.md", false},
+		{"not excluded", SearchPattern{ExcludePaths: []string{"docs/**"}}, "src/network.cs", true},
+		{"extension", SearchPattern{Extensions: []string{"json"}}, "src/appsettings.Production.JSON", true},
+		{"extension with dot", SearchPattern{Extensions: []string{".config"}}, "web.config", true},
+		{"extension mismatch", SearchPattern{Extensions: []string{"json"}}, "src/Program.cs", false},
+		{"restricted without path", SearchPattern{Extensions: []string{"json"}}, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if applies := test.pattern.AppliesToPath(test.path); applies != test.applies {
+				t.Errorf("expected %t but got %t", test.applies, applies)
+			}
+		})
+	}
+}
+
+func TestAcceptsValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern SearchPattern
+		value   string
+		accepts bool
+	}{
+		{"too short", SearchPattern{MinLength: 8}, "abc123", false},
+		{"long enough", SearchPattern{MinLength: 6}, "abc123", true},
+		{"too long", SearchPattern{MaxLength: 4}, "abc123", false},
+		{"length in runes", SearchPattern{MaxLength: 3}, "日本語", true},
+		{"missing class", SearchPattern{CharacterClasses: []string{"lower", "digit"}}, "abcdef", false},
+		{"all classes", SearchPattern{CharacterClasses: []string{"lower", "upper", "digit", "symbol"}}, "aB3$", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if accepts := test.pattern.acceptsValue(test.value); accepts != test.accepts {
+				t.Errorf("expected %t but got %t", test.accepts, accepts)
+			}
+		})
+	}
+}
+
+func TestConstraintValidation(t *testing.T) {
+	invalid := []SearchPattern{
+		{Pattern: "x", Kind: "Bad glob", Paths: []string{"[abc"}},
+		{Pattern: "x", Kind: "Bad class", CharacterClasses: []string{"emoji"}},
+		{Pattern: "x", Kind: "Bad lengths", MinLength: 10, MaxLength: 5},
+	}
+
+	for _, pattern := range invalid {
+		if err := pattern.Validate(); err == nil {
+			t.Errorf("expected \"%s\" to be invalid", pattern.Kind)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package scanning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// contentScan is a scan of one piece of content, which is given its lines in order. Lines are matched as soon as they
+// are added, unless a detector or plugin needs to see all of the scanned lines at once, in which case their matches
+// are held back until the scan budget runs out or the content ends. A streaming scan doesn't hold back the matches
+// of a line's patterns, and only holds lines for the detectors and plugins up to a bounded size.
+type contentScan struct {
+	ctx          context.Context
+	scanner      *Scanner
+	path         string
+	patterns     []compiledPattern
+	detectors    []builtInDetector
+	state        *scanState
+	placeholders *placeholderClassifier
+	honeytokens  map[string]Honeytoken
+
+	// In languages which can be lexed, patterns only match in strings and assigned values, not in comments or names.
+	// Prose is lexed as Markdown, so that link targets and code can be told apart from sentences.
+	codeLexer     *codeLexer
+	markdownLexer *markdownLexer
+
+	wholeContent   bool
+	streaming      bool
+	pending        []scannedLine
+	pendingBytes   int
+	overBudget     bool
+	pluginFailures []string
+	streamReasons  []string
+}
+
+// scannedLine is a line within the scan budget, along with what has been found in it so far
+type scannedLine struct {
+	contentLine
+	patternMatches []Match
+	codeRegions    []codeRegion
+	markdown       *markdownLine
+
+	// The matches already sent for the line by a streaming scan
+	reported []LineMatch
+}
+
+func (scanner *Scanner) newContentScan(ctx context.Context, path string, prose bool) (*contentScan, error) {
+	patterns, err := scanner.compilePatterns(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := &contentScan{
+		ctx:          ctx,
+		scanner:      scanner,
+		path:         path,
+		patterns:     patterns,
+		detectors:    enabledDetectors(scanner.Detectors),
+		state:        newScanState(ctx, scanner.Budget, len(patterns)),
+		placeholders: newPlaceholderClassifier(scanner.Placeholders),
+		honeytokens:  honeytokensByFingerprint(scanner.Honeytokens),
+	}
+	scan.wholeContent = len(scan.detectors) > 0 || len(scanner.Plugins) > 0
+
+	if prose {
+		scan.markdownLexer = &markdownLexer{}
+	} else if language := languageForPath(path); language != nil {
+		scan.codeLexer = &codeLexer{syntax: language}
+	}
+
+	return scan, nil
+}
+
+// needsLines reports whether lines added from now on can still be matched. Once the budget has run out, only
+// honeytokens are looked for, and once the scan has been cancelled nothing is.
+func (scan *contentScan) needsLines() bool {
+	return !scan.overBudget || (len(scan.honeytokens) > 0 && scan.ctx.Err() == nil)
+}
+
+// addLine scans the next line, returning the matches which are ready to be reported
+func (scan *contentScan) addLine(contentLine contentLine) []LineMatch {
+
+	// Carriage returns from CRLF line endings are not part of the line's content
+	line := strings.TrimSuffix(contentLine.text, "\r")
+	contentLine.text = line
+
+	// Honeytokens are looked for even in the lines which are over the scan budget
+	if scan.overBudget || !scan.state.consumeLine(line, contentLine.number) {
+		matches := scan.flush()
+		scan.overBudget = true
+		for _, matchOnLine := range findHoneytokens(line, scan.honeytokens) {
+			matches = append(matches, scan.scanner.newLineMatch(contentLine.number, matchOnLine))
+		}
+		return matches
+	}
+
+	scanned := scannedLine{
+		contentLine:    contentLine,
+		patternMatches: scanLineForPatterns(line, contentLine.number, scan.patterns, scan.state),
+	}
+	if scan.markdownLexer != nil {
+		markdown := scan.markdownLexer.lexLine(line)
+		scanned.markdown = &markdown
+	} else if scan.codeLexer != nil {
+		scanned.codeRegions = scan.codeLexer.lexLine(line)
+	}
+
+	if !scan.wholeContent {
+		return scan.matchLine(&scanned, nil, nil)
+	}
+
+	if !scan.streaming {
+		scan.pending = append(scan.pending, scanned)
+		return nil
+	}
+
+	// Once the held lines would grow past their limit, the detectors and plugins are run over the lines held so far
+	// and no more lines are held for them
+	matches := scan.matchLine(&scanned, nil, nil)
+	if maxBytes := scan.maxPendingBytes(); scan.pendingBytes+len(line) > maxBytes {
+		flushed := scan.flush()
+		scan.wholeContent = false
+		scan.streamReasons = append(scan.streamReasons, fmt.Sprintf(
+			"detectors and plugins stopped at line %d, as at most %d bytes are held for them",
+			contentLine.number,
+			maxBytes))
+		return append(flushed, matches...)
+	}
+
+	scanned.reported = matches
+	scan.pending = append(scan.pending, scanned)
+	scan.pendingBytes += len(line) + 1
+	return matches
+}
+
+// maxPendingBytes is how much content a streaming scan holds for the detectors and plugins
+func (scan *contentScan) maxPendingBytes() int {
+	if scan.state.budget.MaxFileBytes > 0 {
+		return scan.state.budget.MaxFileBytes
+	}
+
+	return DefaultScanBudget().MaxFileBytes
+}
+
+// finish reports the matches which were held back, and the reasons the scan was incomplete
+func (scan *contentScan) finish() ([]LineMatch, []string) {
+	matches := scan.flush()
+	return matches, append(append(scan.state.reasons(), scan.streamReasons...), scan.pluginFailures...)
+}
+
+// flush runs the detectors and plugins over the lines which were held back for them, and matches those lines
+func (scan *contentScan) flush() []LineMatch {
+	if !scan.wholeContent || scan.overBudget {
+		return nil
+	}
+
+	lines := make([]string, len(scan.pending))
+	contentLines := make([]contentLine, len(scan.pending))
+	for i, pending := range scan.pending {
+		lines[i] = pending.text
+		contentLines[i] = pending.contentLine
+	}
+
+	// Detectors look at all of the scanned lines at once, as some need values from several lines
+	detectorMatches := runDetectors(scan.detectors, scan.path, lines, scan.patterns)
+	pluginMatches, pluginFailures := runPlugins(scan.ctx, scan.scanner.Plugins, scan.path, lines, contentLines)
+	scan.pluginFailures = pluginFailures
+
+	var matches []LineMatch
+	for i := range scan.pending {
+		lineMatches := scan.matchLine(&scan.pending[i], detectorMatches[i], pluginMatches[i])
+		if scan.streaming {
+			lineMatches = unreportedMatches(lineMatches, scan.pending[i].reported)
+		}
+		matches = append(matches, lineMatches...)
+	}
+	scan.pending = nil
+	scan.pendingBytes = 0
+
+	return matches
+}
+
+// unreportedMatches drops the matches which overlap a match already sent for their line
+func unreportedMatches(matches []LineMatch, reported []LineMatch) []LineMatch {
+	var result []LineMatch
+	for _, match := range matches {
+		overlaps := false
+		for _, previous := range reported {
+			if match.StartIndex < previous.EndIndex && previous.StartIndex < match.EndIndex {
+				overlaps = true
+				break
+			}
+		}
+
+		if !overlaps {
+			result = append(result, match)
+		}
+	}
+
+	return result
+}
+
+// matchLine combines everything found in a line, dropping placeholders and merging matches of the same value
+func (scan *contentScan) matchLine(line *scannedLine, detectorMatches []Match, pluginMatches []Match) []LineMatch {
+	patternMatches := line.patternMatches
+	if scan.codeLexer != nil {
+		patternMatches = filterMatchesToCode(patternMatches, line.codeRegions)
+	}
+
+	interpolationLine := line.text
+	lineMatches := append(append(patternMatches, detectorMatches...), pluginMatches...)
+	if line.markdown != nil {
+		lineMatches = append(filterMatchesToProse(lineMatches, line.text, line.markdown),
+			findProseCredentials(line.text, line.markdown)...)
+		interpolationLine = line.markdown.withoutLinkBrackets(line.text)
+	}
+
+	var matchesOnLine []Match
+	for _, matchOnLine := range lineMatches {
+
+		// Variable references and dummy values aren't secrets
+		value := matchOnLine.value
+		if matchOnLine.decodedValue != "" {
+			value = matchOnLine.decodedValue
+		}
+		if !scan.placeholders.isPlaceholder(value) &&
+			!scan.placeholders.isInsideInterpolation(interpolationLine, matchOnLine.StartIndex, matchOnLine.EndIndex) {
+			matchesOnLine = append(matchesOnLine, matchOnLine)
+		}
+	}
+
+	// When several patterns match the same value, only report it once
+	var matches []LineMatch
+	matchesOnLine = resolveOverlappingMatches(line.text, matchesOnLine)
+	for _, matchOnLine := range withHoneytokens(matchesOnLine, findHoneytokens(line.text, scan.honeytokens)) {
+		scan.scanner.applyTriageModel(scan.path, &matchOnLine)
+		matches = append(matches, scan.scanner.newLineMatch(line.number, matchOnLine))
+	}
+
+	return matches
+}
@@ -0,0 +1,157 @@
+package scanning
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// detectSecretsPlugins holds patterns equivalent to detect-secrets' built-in plugins, which are written in Python
+// rather than as rules
+var detectSecretsPlugins = map[string][]SearchPattern{
+	"AWSKeyDetector": {
+		{Pattern: `(?:A3T[A-Z0-9]|ABIA|ACCA|AKIA|ASIA)[0-9A-Z]{16}`, Kind: "AWS access key ID"},
+		{Pattern: `(?i)aws.{0,20}?(?:key|pwd|pw|password|pass|token).{0,20}?['"](?P<secret>[0-9a-zA-Z/+]{40})['"]`, Kind: "AWS secret access key"},
+	},
+	"ArtifactoryDetector": {
+		{Pattern: `(?:\s|=|:|"|^)(?P<secret>AKC[a-zA-Z0-9]{10,})(?:\s|"|$)`, Kind: "Artifactory token"},
+		{Pattern: `(?:\s|=|:|"|^)(?P<secret>AP[\dABCDEF][a-zA-Z0-9]{8,})(?:\s|"|$)`, Kind: "Artifactory password"},
+	},
+	"AzureStorageKeyDetector": {
+		{Pattern: `AccountKey=(?P<secret>[a-zA-Z0-9+/=]{88})`, Kind: "Azure storage account key"},
+	},
+	"BasicAuthDetector": {
+		{Pattern: `://[^{}\s]+:(?P<secret>[^{}\s]+)@`, Kind: "Basic auth credentials"},
+	},
+	"DiscordBotTokenDetector": {
+		{Pattern: `[MNO][a-zA-Z\d_-]{23,25}\.[a-zA-Z\d_-]{6}\.[a-zA-Z\d_-]{27}`, Kind: "Discord bot token"},
+	},
+	"GitHubTokenDetector": {
+		{Pattern: `(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9_]{36}`, Kind: "GitHub token"},
+	},
+	"GitLabTokenDetector": {
+		{Pattern: `(?:glpat|gldt|glft|glsoat|glrt)-[A-Za-z0-9_\-]{20,50}`, Kind: "GitLab token"},
+	},
+	"JwtTokenDetector": {
+		{Pattern: `eyJ[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*`, Kind: "JSON Web Token"},
+	},
+	"MailchimpDetector": {
+		{Pattern: `[0-9a-z]{32}-us[0-9]{1,2}`, Kind: "Mailchimp access key"},
+	},
+	"NpmDetector": {
+		{Pattern: `//.+/:_authToken=\s*(?P<secret>npm_.+|[A-Fa-f0-9-]{36})`, Kind: "NPM token"},
+	},
+	"OpenAIDetector": {
+		{Pattern: `sk-[A-Za-z0-9-_]*[A-Za-z0-9]{20}T3BlbkFJ[A-Za-z0-9]{20}`, Kind: "OpenAI token"},
+	},
+	"PrivateKeyDetector": {
+		{Pattern: `BEGIN (?:DSA |EC |OPENSSH |RSA |SSH2 ENCRYPTED )?PRIVATE KEY|BEGIN PGP PRIVATE KEY BLOCK|PuTTY-User-Key-File-2`, Kind: "Private key"},
+	},
+	"PypiTokenDetector": {
+		{Pattern: `pypi-AgEIcHlwaS5vcmc[A-Za-z0-9-_]{70,}`, Kind: "PyPI token"},
+	},
+	"SendGridDetector": {
+		{Pattern: `SG\.[a-zA-Z0-9_-]{22}\.[a-zA-Z0-9_-]{43}`, Kind: "SendGrid API key"},
+	},
+	"SlackDetector": {
+		{Pattern: `xox(?:a|b|p|o|s|r)-(?:\d+-)+[a-z0-9]+`, Kind: "Slack token"},
+		{Pattern: `https://hooks\.slack\.com/services/T[a-zA-Z0-9_]+/B[a-zA-Z0-9_]+/[a-zA-Z0-9_]+`, Kind: "Slack webhook"},
+	},
+	"SquareOAuthDetector": {
+		{Pattern: `sq0csp-[0-9A-Za-z\\\-_]{43}`, Kind: "Square OAuth secret"},
+	},
+	"StripeDetector": {
+		{Pattern: `(?:r|s)k_live_[0-9a-zA-Z]{24}`, Kind: "Stripe access key"},
+	},
+	"TelegramBotTokenDetector": {
+		{Pattern: `^\d{8,10}:[0-9A-Za-z_-]{35}$`, Kind: "Telegram bot token"},
+	},
+	"TwilioKeyDetector": {
+		{Pattern: `AC[a-z0-9]{32}`, Kind: "Twilio account SID"},
+		{Pattern: `SK[a-z0-9]{32}`, Kind: "Twilio API key"},
+	},
+	"Base64HighEntropyString": {
+		{Pattern: `["'](?P<secret>[A-Za-z0-9+/]{20,}={0,2})["']`, Kind: "High entropy base64 string", Entropy: 4.5},
+	},
+	"HexHighEntropyString": {
+		{Pattern: `["'](?P<secret>[0-9a-fA-F]{20,})["']`, Kind: "High entropy hex string", Entropy: 3.0},
+	},
+	"KeywordDetector": {
+		{
+			Pattern: `(?i)(?:api_?key|auth_?key|service_?key|account_?key|db_?key|database_?key|priv_?key|private_?key|client_?key|db_?pass|database_?pass|key_?pass|password|passwd|pwd|secret|contraseña|contrasena)\w*["']?\s*(?:=|:=|:|=>|==|!=)\s*["'](?P<secret>[^"'\s]+)["']`,
+			Kind:    "Secret keyword",
+		},
+	},
+}
+
+type detectSecretsBaseline struct {
+	PluginsUsed []map[string]interface{} `json:"plugins_used"`
+	FiltersUsed []map[string]interface{} `json:"filters_used"`
+}
+
+// ImportDetectSecretsPlugins converts the plugins enabled in a detect-secrets baseline into search patterns. Built-in
+// plugins are mapped to equivalent patterns, and custom plugins can't be imported.
+func ImportDetectSecretsPlugins(data []byte) (*PatternImport, error) {
+	var baseline detectSecretsBaseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, err
+	}
+
+	result := &PatternImport{}
+
+	// Regex filters exclude values from every plugin
+	var exclusions []string
+	for _, filter := range baseline.FiltersUsed {
+		path, _ := filter["path"].(string)
+		switch {
+		case strings.HasSuffix(path, "regex.should_exclude_secret"):
+			exclusions = append(exclusions, jsonStrings(filter["pattern"])...)
+		case strings.HasSuffix(path, "regex.should_exclude_line"):
+			result.warn("line exclusion filters will be applied to the matched value")
+			exclusions = append(exclusions, jsonStrings(filter["pattern"])...)
+		case strings.HasSuffix(path, "regex.should_exclude_file"):
+			result.warn("file exclusion filters are not supported and have been ignored")
+		}
+	}
+
+	for _, plugin := range baseline.PluginsUsed {
+		name, _ := plugin["name"].(string)
+		patterns, ok := detectSecretsPlugins[name]
+		if !ok {
+			result.skip(name, "there is no equivalent pattern for this plugin")
+			continue
+		}
+
+		for _, pattern := range patterns {
+			pattern.Exclusions = append([]string{}, exclusions...)
+
+			if limit, ok := plugin["limit"].(float64); ok {
+				pattern.Entropy = limit
+			}
+
+			if keywordExclude, ok := plugin["keyword_exclude"].(string); ok && keywordExclude != "" {
+				result.warn("keyword exclusions for %s will be applied to the matched value", name)
+				pattern.Exclusions = append(pattern.Exclusions, keywordExclude)
+			}
+
+			result.add(name, pattern)
+		}
+	}
+
+	return result, nil
+}
+
+func jsonStrings(value interface{}) []string {
+	var result []string
+	switch typedValue := value.(type) {
+	case string:
+		result = append(result, typedValue)
+	case []interface{}:
+		for _, item := range typedValue {
+			if stringValue, ok := item.(string); ok {
+				result = append(result, stringValue)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,170 @@
+package scanning
+
+import (
+	"Orca/pkg/yaml"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// DetectorOptions turns the built-in detectors on and off. Detectors are enabled by default, and can be turned off
+// all at once or by name, see builtInDetectors.
+type DetectorOptions struct {
+	Disabled          bool     `json:"disabled,omitempty"`
+	DisabledDetectors []string `json:"disabledDetectors,omitempty"`
+}
+
+// builtInDetector finds secrets which can't be described by a single regex, such as values which need to be decoded or
+// which are only secrets when found alongside another value
+type builtInDetector struct {
+	name        string
+	severity    Severity
+	remediation string
+	detect      func(input *detectorInput) []detection
+}
+
+// detectorInput is the content being scanned, along with its path if it is a file
+type detectorInput struct {
+	path  string
+	lines []string
+
+	yamlParsed    bool
+	yamlDocuments []*yaml.Node
+}
+
+// yaml parses the content as YAML the first time it is needed, returning nil if it isn't a YAML file or can't
+// be parsed
+func (input *detectorInput) yaml() []*yaml.Node {
+	if input.yamlParsed {
+		return input.yamlDocuments
+	}
+	input.yamlParsed = true
+
+	extension := strings.ToLower(path.Ext(input.path))
+	if input.path != "" && extension != ".yaml" && extension != ".yml" {
+		return nil
+	}
+
+	documents, err := yaml.Parse([]byte(strings.Join(input.lines, "\n")))
+	if err == nil {
+		input.yamlDocuments = documents
+	}
+
+	return input.yamlDocuments
+}
+
+// detection is a value found by a detector, as a byte range within one of the lines it was given. The location names
+// where the value is within a structured file, and values which were encoded in the file have their decoded value.
+type detection struct {
+	lineIndex    int
+	startIndex   int
+	endIndex     int
+	kind         string
+	location     string
+	decodedValue string
+}
+
+func newDetection(lineIndex int, startIndex int, endIndex int, kind string) detection {
+	return detection{lineIndex: lineIndex, startIndex: startIndex, endIndex: endIndex, kind: kind}
+}
+
+var builtInDetectors = []builtInDetector{
+	awsAccessKeyPairDetector,
+	azureStorageKeyDetector,
+	azureSasTokenDetector,
+	azureClientSecretDetector,
+	gcpServiceAccountDetector,
+	dockerfileDetector,
+	composeDetector,
+	kubernetesSecretDetector,
+	helmValuesDetector,
+	terraformStateDetector,
+	terraformVariablesDetector,
+	cloudFormationNoEchoDetector,
+}
+
+// BuiltInDetectorNames lists the names which can be used to turn off built-in detectors
+func BuiltInDetectorNames() []string {
+	var names []string
+	for _, detector := range builtInDetectors {
+		names = append(names, detector.name)
+	}
+
+	return names
+}
+
+// Validate checks that the disabled detectors exist, so that a typo doesn't leave a detector on
+func (options *DetectorOptions) Validate() error {
+	names := BuiltInDetectorNames()
+	for _, name := range options.DisabledDetectors {
+		if !containsString(names, name) {
+			return fmt.Errorf("unknown detector \"%s\"", name)
+		}
+	}
+
+	return nil
+}
+
+func enabledDetectors(options DetectorOptions) []builtInDetector {
+	if options.Disabled {
+		return nil
+	}
+
+	var detectors []builtInDetector
+	for _, detector := range builtInDetectors {
+		if !containsString(options.DisabledDetectors, detector.name) {
+			detectors = append(detectors, detector)
+		}
+	}
+
+	return detectors
+}
+
+// runDetectors runs the detectors over the lines, returning the matches on each line. Decoded values are checked
+// against the patterns too, and the kinds of any which match are added to the match's secondary kinds.
+func runDetectors(
+	detectors []builtInDetector,
+	filePath string,
+	lines []string,
+	patterns []compiledPattern) map[int][]Match {
+
+	matches := map[int][]Match{}
+	input := &detectorInput{path: filePath, lines: lines}
+	for _, detector := range detectors {
+		for _, found := range detector.detect(input) {
+			line := lines[found.lineIndex]
+			startColumn := utf8.RuneCountInString(line[:found.startIndex])
+			value := line[found.startIndex:found.endIndex]
+
+			var secondaryKinds []string
+			if found.decodedValue != "" {
+				for _, pattern := range patterns {
+					if pattern.regex.MatchString(found.decodedValue) && !pattern.CanIgnore(found.decodedValue) &&
+						!containsString(secondaryKinds, pattern.Kind) {
+						secondaryKinds = append(secondaryKinds, pattern.Kind)
+					}
+				}
+			}
+
+			matches[found.lineIndex] = append(matches[found.lineIndex], Match{
+				StartIndex:     found.startIndex,
+				EndIndex:       found.endIndex,
+				StartColumn:    startColumn,
+				EndColumn:      startColumn + utf8.RuneCountInString(value),
+				value:          value,
+				decodedValue:   found.decodedValue,
+				Kind:           found.kind,
+				Location:       found.location,
+				Severity:       detector.severity,
+				Remediation:    detector.remediation,
+				SecondaryKinds: secondaryKinds,
+
+				// Detectors validate what they find, so are more specific than any regex
+				specificity: maxSpecificity,
+			})
+		}
+	}
+
+	return matches
+}
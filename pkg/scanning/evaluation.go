@@ -0,0 +1,307 @@
+package scanning
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CorpusLabelsSuffix is added to a corpus file's name to give the name of its labels file. Corpus files without a
+// labels file are expected to have no findings.
+const CorpusLabelsSuffix = ".labels.json"
+
+// CorpusLabel is a finding expected in a corpus file
+type CorpusLabel struct {
+	Line int    `json:"line"`
+	Kind string `json:"kind"`
+}
+
+// EvaluationOutcome is whether a finding was expected and found
+type EvaluationOutcome string
+
+const (
+	OutcomeTruePositive  EvaluationOutcome = "true-positive"
+	OutcomeFalsePositive EvaluationOutcome = "false-positive"
+	OutcomeFalseNegative EvaluationOutcome = "false-negative"
+)
+
+// EvaluationFinding is a false positive or false negative, so that they can be looked into
+type EvaluationFinding struct {
+	Path    string            `json:"path"`
+	Line    int               `json:"line"`
+	Kind    string            `json:"kind"`
+	Outcome EvaluationOutcome `json:"outcome"`
+}
+
+// KindEvaluation counts the findings of one kind of pattern or detector
+type KindEvaluation struct {
+	Kind           string `json:"kind"`
+	TruePositives  int    `json:"truePositives"`
+	FalsePositives int    `json:"falsePositives"`
+	FalseNegatives int    `json:"falseNegatives"`
+}
+
+// Precision is the proportion of findings which were expected. A kind which found nothing has perfect precision.
+func (evaluation *KindEvaluation) Precision() float64 {
+	return ratio(evaluation.TruePositives, evaluation.TruePositives+evaluation.FalsePositives)
+}
+
+// Recall is the proportion of expected findings which were found. A kind with nothing to find has perfect recall.
+func (evaluation *KindEvaluation) Recall() float64 {
+	return ratio(evaluation.TruePositives, evaluation.TruePositives+evaluation.FalseNegatives)
+}
+
+func ratio(numerator int, denominator int) float64 {
+	if denominator == 0 {
+		return 1
+	}
+
+	return float64(numerator) / float64(denominator)
+}
+
+func (evaluation *KindEvaluation) add(outcome EvaluationOutcome) {
+	switch outcome {
+	case OutcomeTruePositive:
+		evaluation.TruePositives++
+	case OutcomeFalsePositive:
+		evaluation.FalsePositives++
+	case OutcomeFalseNegative:
+		evaluation.FalseNegatives++
+	}
+}
+
+// Evaluation is the result of scanning a labelled corpus
+type Evaluation struct {
+	Files           int                 `json:"files"`
+	Bytes           int                 `json:"bytes"`
+	IncompleteFiles int                 `json:"incompleteFiles"`
+	DurationSeconds float64             `json:"durationSeconds"`
+	Totals          KindEvaluation      `json:"totals"`
+	Kinds           []KindEvaluation    `json:"kinds"`
+	Findings        []EvaluationFinding `json:"findings,omitempty"`
+}
+
+// BytesPerSecond is the scanner's throughput over the corpus
+func (evaluation *Evaluation) BytesPerSecond() float64 {
+	if evaluation.DurationSeconds == 0 {
+		return 0
+	}
+
+	return float64(evaluation.Bytes) / evaluation.DurationSeconds
+}
+
+// Kind returns the evaluation of a kind, which is empty if the kind wasn't found or expected
+func (evaluation *Evaluation) Kind(kind string) KindEvaluation {
+	for _, kindEvaluation := range evaluation.Kinds {
+		if kindEvaluation.Kind == kind {
+			return kindEvaluation
+		}
+	}
+
+	return KindEvaluation{Kind: kind}
+}
+
+func ParseEvaluation(data []byte) (*Evaluation, error) {
+	var evaluation Evaluation
+	if err := json.Unmarshal(data, &evaluation); err != nil {
+		return nil, fmt.Errorf("invalid evaluation: %v", err)
+	}
+
+	return &evaluation, nil
+}
+
+type corpusFinding struct {
+	path string
+	line int
+	kind string
+}
+
+// EvaluateCorpus scans every file in a directory, comparing the matches with each file's labels. A match counts as a
+// finding of its own kind and of each of its secondary kinds, so that every pattern which matched is accounted for.
+func (scanner *Scanner) EvaluateCorpus(directory string) (*Evaluation, error) {
+	evaluation := &Evaluation{}
+	expected := map[corpusFinding]bool{}
+	found := map[corpusFinding]bool{}
+	var scanDuration time.Duration
+
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && path != directory {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.HasSuffix(path, CorpusLabelsSuffix) {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		labels, err := readCorpusLabels(path + CorpusLabelsSuffix)
+		if err != nil {
+			return err
+		}
+		for _, label := range labels {
+			expected[corpusFinding{path: relativePath, line: label.Line, kind: label.Kind}] = true
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+		result, err := scanner.ScanFileContent(relativePath, string(content))
+		scanDuration += time.Since(started)
+		if err != nil {
+			return err
+		}
+
+		evaluation.Files++
+		evaluation.Bytes += len(content)
+		if result.IsIncomplete() {
+			evaluation.IncompleteFiles++
+		}
+
+		for _, match := range result.Matches {
+			for _, kind := range append([]string{match.Kind}, match.SecondaryKinds...) {
+				found[corpusFinding{path: relativePath, line: match.LineNumber, kind: kind}] = true
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation.DurationSeconds = scanDuration.Seconds()
+
+	kinds := map[string]*KindEvaluation{}
+	record := func(finding corpusFinding, outcome EvaluationOutcome) {
+		if kinds[finding.kind] == nil {
+			kinds[finding.kind] = &KindEvaluation{Kind: finding.kind}
+		}
+		kinds[finding.kind].add(outcome)
+		evaluation.Totals.add(outcome)
+
+		if outcome != OutcomeTruePositive {
+			evaluation.Findings = append(evaluation.Findings, EvaluationFinding{
+				Path:    finding.path,
+				Line:    finding.line,
+				Kind:    finding.kind,
+				Outcome: outcome,
+			})
+		}
+	}
+
+	for finding := range found {
+		if expected[finding] {
+			record(finding, OutcomeTruePositive)
+		} else {
+			record(finding, OutcomeFalsePositive)
+		}
+	}
+
+	for finding := range expected {
+		if !found[finding] {
+			record(finding, OutcomeFalseNegative)
+		}
+	}
+
+	for _, kindEvaluation := range kinds {
+		evaluation.Kinds = append(evaluation.Kinds, *kindEvaluation)
+	}
+
+	sort.Slice(evaluation.Kinds, func(i, j int) bool {
+		return evaluation.Kinds[i].Kind < evaluation.Kinds[j].Kind
+	})
+
+	sort.Slice(evaluation.Findings, func(i, j int) bool {
+		a, b := evaluation.Findings[i], evaluation.Findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Kind < b.Kind
+	})
+
+	return evaluation, nil
+}
+
+func readCorpusLabels(labelsFile string) ([]CorpusLabel, error) {
+	data, err := ioutil.ReadFile(labelsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var labels []CorpusLabel
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("invalid labels in %s: %v", labelsFile, err)
+	}
+
+	for _, label := range labels {
+		if label.Line < 1 || label.Kind == "" {
+			return nil, fmt.Errorf("invalid label in %s: labels need a line number and a kind", labelsFile)
+		}
+	}
+
+	return labels, nil
+}
+
+// EvaluationChange compares a kind's results between two evaluations
+type EvaluationChange struct {
+	Kind     string
+	Previous KindEvaluation
+	Current  KindEvaluation
+}
+
+func (change *EvaluationChange) Changed() bool {
+	return change.Previous.TruePositives != change.Current.TruePositives ||
+		change.Previous.FalsePositives != change.Current.FalsePositives ||
+		change.Previous.FalseNegatives != change.Current.FalseNegatives
+}
+
+// CompareEvaluations lists the changes to each kind between a previous evaluation and the current one, including kinds
+// which are only in one of them
+func CompareEvaluations(previous *Evaluation, current *Evaluation) []EvaluationChange {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, evaluation := range []*Evaluation{previous, current} {
+		for _, kindEvaluation := range evaluation.Kinds {
+			if !seen[kindEvaluation.Kind] {
+				seen[kindEvaluation.Kind] = true
+				kinds = append(kinds, kindEvaluation.Kind)
+			}
+		}
+	}
+	sort.Strings(kinds)
+
+	var changes []EvaluationChange
+	for _, kind := range kinds {
+		change := EvaluationChange{Kind: kind, Previous: previous.Kind(kind), Current: current.Kind(kind)}
+		if change.Changed() {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
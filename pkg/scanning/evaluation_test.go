@@ -0,0 +1,78 @@
+package scanning
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateCorpus(t *testing.T) {
+	directory, err := ioutil.TempDir("", "orca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	files := map[string]string{
+		"config.ini":                      "user = orca\npassword = hunter2\ntoken = tok_1234\n",
+		"config.ini" + CorpusLabelsSuffix: `[{"line": 2, "kind": "Password"}, {"line": 3, "kind": "Token"}]`,
+		"docs/readme.txt":                 "the password is hunter2\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := &Scanner{
+		Patterns:  []SearchPattern{{Pattern: `hunter2`, Kind: "Password"}},
+		Detectors: DetectorOptions{Disabled: true},
+	}
+	evaluation, err := scanner.EvaluateCorpus(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if evaluation.Files != 2 {
+		t.Errorf("expected 2 files but got %d", evaluation.Files)
+	}
+
+	expectedKinds := []KindEvaluation{
+		{Kind: "Password", TruePositives: 1, FalsePositives: 1},
+		{Kind: "Token", FalseNegatives: 1},
+	}
+	if !reflect.DeepEqual(evaluation.Kinds, expectedKinds) {
+		t.Errorf("expected %+v but got %+v", expectedKinds, evaluation.Kinds)
+	}
+
+	expectedFindings := []EvaluationFinding{
+		{Path: "config.ini", Line: 3, Kind: "Token", Outcome: OutcomeFalseNegative},
+		{Path: "docs/readme.txt", Line: 1, Kind: "Password", Outcome: OutcomeFalsePositive},
+	}
+	if !reflect.DeepEqual(evaluation.Findings, expectedFindings) {
+		t.Errorf("expected %+v but got %+v", expectedFindings, evaluation.Findings)
+	}
+
+	if precision, recall := evaluation.Totals.Precision(), evaluation.Totals.Recall(); precision != 0.5 || recall != 0.5 {
+		t.Errorf("expected a precision and recall of 0.5 but got %v and %v", precision, recall)
+	}
+
+	// Adding a pattern for the tokens should only change the tokens' results
+	scanner.Patterns = append(scanner.Patterns, SearchPattern{Pattern: `tok_\d+`, Kind: "Token"})
+	improved, err := scanner.EvaluateCorpus(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := CompareEvaluations(evaluation, improved)
+	if len(changes) != 1 || changes[0].Kind != "Token" || changes[0].Current.TruePositives != 1 ||
+		changes[0].Current.FalseNegatives != 0 {
+		t.Errorf("expected the tokens to be found but got %+v", changes)
+	}
+}
@@ -0,0 +1,185 @@
+package scanning
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SkippedFile is a file which wasn't scanned because it is vendored, generated or minified
+type SkippedFile struct {
+	Path         string
+	PermalinkURL string
+	Reason       string
+}
+
+const (
+	linguistGenerated = "linguist-generated"
+	linguistVendored  = "linguist-vendored"
+)
+
+var vendoredDirectories = []string{
+	"node_modules",
+	"bower_components",
+	"jspm_packages",
+	"vendor",
+	"third_party",
+	"thirdparty",
+	"Pods",
+	"Carthage",
+	".yarn",
+}
+
+var lockFiles = []string{
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"packages.lock.json",
+	"paket.lock",
+	"project.assets.json",
+	"Gemfile.lock",
+	"Cargo.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"go.sum",
+	"mix.lock",
+	"pubspec.lock",
+	"Podfile.lock",
+}
+
+var generatedFileNames = []string{
+	"*.pb.go",
+	"*.pb.cc",
+	"*.pb.h",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*_pb.js",
+	"*_grpc_pb.js",
+	"*.Designer.cs",
+	"*.designer.cs",
+	"*.g.cs",
+	"*.g.i.cs",
+	"*.generated.cs",
+	"*_generated.go",
+	"zz_generated.*.go",
+}
+
+var minifiedFileNames = []string{
+	"*.min.js",
+	"*.min.mjs",
+	"*.min.css",
+	"*.js.map",
+	"*.css.map",
+}
+
+// minifiedContentExtensions are the kinds of file which are checked for minified content. Config files such as
+// .json and .env are often written on one long line, and are where credentials are most likely to be.
+var minifiedContentExtensions = []string{
+	".js",
+	".mjs",
+	".cjs",
+	".css",
+	".map",
+}
+
+// generatedHeader matches the markers code generators put at the top of files
+var generatedHeader = regexp.MustCompile(
+	`(?i)code generated .* do not edit|<auto-generated|@generated|autogenerated file|this file (?:was|is) (?:auto-?)?generated`)
+
+const (
+	generatedHeaderLines   = 10
+	minifiedMinimumBytes   = 1024
+	minifiedAverageLineLen = 500
+)
+
+// fileSkipper decides which files are vendored, generated or minified using the repository's .gitattributes and
+// built-in heuristics. Setting linguist-generated or linguist-vendored to false for a path turns off the heuristics for
+// it, as it does for GitHub's linguist. A nil skipper doesn't skip anything.
+type fileSkipper struct {
+	attributes *gitAttributes
+}
+
+// skipReasonForPath checks if a file should be skipped based on its path alone, returning why or an empty string
+func (skipper *fileSkipper) skipReasonForPath(filePath string) string {
+	if skipper == nil {
+		return ""
+	}
+
+	generated, generatedSpecified := skipper.attributes.isSet(filePath, linguistGenerated)
+	vendored, vendoredSpecified := skipper.attributes.isSet(filePath, linguistVendored)
+
+	if generated {
+		return "marked as " + linguistGenerated + " in " + GitAttributesPath
+	}
+
+	if vendored {
+		return "marked as " + linguistVendored + " in " + GitAttributesPath
+	}
+
+	if !vendoredSpecified {
+		for _, segment := range strings.Split(path.Dir(filePath), "/") {
+			for _, directory := range vendoredDirectories {
+				if segment == directory {
+					return "vendored dependencies in " + directory + "/"
+				}
+			}
+		}
+	}
+
+	if generatedSpecified {
+		return ""
+	}
+
+	for _, lockFile := range lockFiles {
+		if path.Base(filePath) == lockFile {
+			return "dependency lock file"
+		}
+	}
+
+	if matchesAnyGlob(generatedFileNames, filePath) {
+		return "generated file name"
+	}
+
+	if matchesAnyGlob(minifiedFileNames, filePath) {
+		return "minified file name"
+	}
+
+	return ""
+}
+
+// skipReasonForContent checks if a file should be skipped based on its content, returning why or an empty string
+func (skipper *fileSkipper) skipReasonForContent(filePath string, content string) string {
+	if skipper == nil {
+		return ""
+	}
+
+	if _, generatedSpecified := skipper.attributes.isSet(filePath, linguistGenerated); generatedSpecified {
+		return ""
+	}
+
+	lines := strings.SplitN(content, "\n", generatedHeaderLines+1)
+	if generatedHeader.MatchString(strings.Join(lines[:minInt(len(lines), generatedHeaderLines)], "\n")) {
+		return "generated file header"
+	}
+
+	if isMinifiableFile(filePath) &&
+		len(content) >= minifiedMinimumBytes &&
+		len(content)/(strings.Count(content, "\n")+1) > minifiedAverageLineLen {
+		return "minified content"
+	}
+
+	return ""
+}
+
+func isMinifiableFile(filePath string) bool {
+	extension := path.Ext(filePath)
+	for _, minifiable := range minifiedContentExtensions {
+		if extension == minifiable {
+			return true
+		}
+	}
+
+	return false
+}
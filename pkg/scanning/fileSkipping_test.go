@@ -0,0 +1,79 @@
+package scanning
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSkipReasonForPath(t *testing.T) {
+	attributes := parseGitAttributes(strings.Join([]string{
+		"# Generated clients",
+		"src/Clients/** linguist-generated",
+		"third_party/ours/** -linguist-vendored",
+		"*.lock -linguist-generated",
+		"docs/api.json linguist-vendored=true",
+	}, "\n"))
+	skipper := &fileSkipper{attributes: attributes}
+
+	tests := []struct {
+		path    string
+		skipped bool
+	}{
+		{"src/Program.cs", false},
+		{"src/Clients/Api/Client.cs", true},
+		{"docs/api.json", true},
+		{"web/node_modules/left-pad/index.js", true},
+		{"vendor/github.com/google/go-github/github.go", true},
+		{"third_party/ours/config.go", false},
+		{"package-lock.json", true},
+		{"yarn.lock", false},
+		{"api/service.pb.go", true},
+		{"Forms/MainForm.Designer.cs", true},
+		{"wwwroot/js/site.min.js", true},
+		{"wwwroot/js/site.js", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			reason := skipper.skipReasonForPath(test.path)
+			if (reason != "") != test.skipped {
+				t.Errorf("expected skipped to be %t but got reason \"%s\"", test.skipped, reason)
+			}
+		})
+	}
+}
+
+func TestSkipReasonForContent(t *testing.T) {
+	skipper := &fileSkipper{}
+
+	serviceAccount := `{"type": "service_account", "private_key": "` + strings.Repeat("A", 1600) + `"}`
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		skipped bool
+	}{
+		{"go generated header", "api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n", true},
+		{"c# auto-generated header", "Api.cs", "//------\n// <auto-generated>\n//------\n", true},
+		{"minified", "bundle.js", "var a=" + strings.Repeat("1+", 1000) + "1;\n", true},
+		{"minified style sheet", "site.css", "a{" + strings.Repeat("b:c;", 400) + "}\n", true},
+		{"one line json", "sa-key.json", serviceAccount, false},
+		{"one line env", ".env", "TOKEN=" + strings.Repeat("a", 1200), false},
+		{"one line without an extension", "file", "var a=" + strings.Repeat("1+", 1000) + "1;\n", false},
+		{"source", "main.go", "package main\n\nfunc main() {\n}\n", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason := skipper.skipReasonForContent(test.path, test.content)
+			if (reason != "") != test.skipped {
+				t.Errorf("expected skipped to be %t but got reason \"%s\"", test.skipped, reason)
+			}
+		})
+	}
+
+	var disabled *fileSkipper
+	if reason := disabled.skipReasonForPath("node_modules/index.js"); reason != "" {
+		t.Errorf("expected a nil skipper not to skip files but got \"%s\"", reason)
+	}
+}
@@ -0,0 +1,79 @@
+package scanning
+
+import (
+	"strings"
+)
+
+// GitAttributesPath is the file in the root of a repository which assigns attributes to paths
+const GitAttributesPath = ".gitattributes"
+
+type gitAttributeRule struct {
+	pattern    string
+	attributes map[string]string
+}
+
+// gitAttributes holds the rules from a .gitattributes file. Set attributes have the value "true", unset attributes
+// (-name) have the value "false", and !name makes an attribute unspecified again.
+type gitAttributes struct {
+	rules []gitAttributeRule
+}
+
+func parseGitAttributes(content string) *gitAttributes {
+	result := &gitAttributes{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := gitAttributeRule{
+			pattern:    strings.Trim(fields[0], "\""),
+			attributes: map[string]string{},
+		}
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "-"):
+				rule.attributes[field[1:]] = "false"
+			case strings.HasPrefix(field, "!"):
+				rule.attributes[field[1:]] = ""
+			case strings.Contains(field, "="):
+				parts := strings.SplitN(field, "=", 2)
+				rule.attributes[parts[0]] = parts[1]
+			default:
+				rule.attributes[field] = "true"
+			}
+		}
+
+		result.rules = append(result.rules, rule)
+	}
+
+	return result
+}
+
+// value returns the value of an attribute for a path, and false if the attribute is unspecified. Later rules override
+// earlier ones, as they do in git.
+func (attributes *gitAttributes) value(path string, name string) (string, bool) {
+	if attributes == nil {
+		return "", false
+	}
+
+	value := ""
+	for _, rule := range attributes.rules {
+		ruleValue, ok := rule.attributes[name]
+		if ok && matchesAnyGlob([]string{rule.pattern}, path) {
+			value = ruleValue
+		}
+	}
+
+	return value, value != ""
+}
+
+// isSet checks if a boolean attribute is true for a path, and whether it was specified at all
+func (attributes *gitAttributes) isSet(path string, name string) (bool, bool) {
+	value, ok := attributes.value(path, name)
+	if !ok {
+		return false, false
+	}
+
+	return value != "false", true
+}
@@ -0,0 +1,161 @@
+package scanning
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ImportGitSecretsPatterns converts a git-secrets pattern list into search patterns. Each line can be a
+// "git secrets --add" command, a "secrets.patterns" or "secrets.allowed" entry from git config, or a bare pattern.
+// Allowed patterns become exclusions for every pattern.
+func ImportGitSecretsPatterns(data []byte) (*PatternImport, error) {
+	result := &PatternImport{}
+
+	var expressions []string
+	var allowed []string
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNumber := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "git secrets ") || strings.HasPrefix(line, "git-secrets "):
+			expression, isAllowed, err := parseGitSecretsCommand(line)
+			if err != nil {
+				result.skip(fmt.Sprintf("line %d", lineNumber), err.Error())
+			} else if isAllowed {
+				allowed = append(allowed, expression)
+			} else {
+				expressions = append(expressions, expression)
+			}
+		case strings.HasPrefix(line, "secrets.patterns "):
+			expressions = append(expressions, strings.TrimSpace(strings.TrimPrefix(line, "secrets.patterns ")))
+		case strings.HasPrefix(line, "secrets.allowed "):
+			allowed = append(allowed, strings.TrimSpace(strings.TrimPrefix(line, "secrets.allowed ")))
+		case strings.HasPrefix(line, "secrets.providers "):
+			result.skip(fmt.Sprintf("line %d", lineNumber), "providers run a command to list patterns and can't be imported")
+		default:
+			expressions = append(expressions, line)
+		}
+	}
+
+	// Allowed patterns are only used as exclusions, so an invalid one would break every pattern
+	for _, expression := range allowed {
+		if _, err := compilePattern(expression); err != nil {
+			return nil, fmt.Errorf("invalid allowed pattern \"%s\": %v", expression, err)
+		}
+	}
+
+	for i, expression := range expressions {
+		kind := fmt.Sprintf("git-secrets pattern %d", i+1)
+		result.add(kind, SearchPattern{
+			Pattern:    expression,
+			Kind:       kind,
+			Exclusions: append([]string{}, allowed...),
+		})
+	}
+
+	return result, nil
+}
+
+// parseGitSecretsCommand parses the pattern from a "git secrets --add" command
+func parseGitSecretsCommand(command string) (expression string, isAllowed bool, err error) {
+	arguments, err := splitShellArguments(command)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Skip "git secrets" or "git-secrets"
+	if arguments[0] == "git" {
+		arguments = arguments[1:]
+	}
+
+	isAdd := false
+	isLiteral := false
+	var values []string
+	for _, argument := range arguments[1:] {
+		switch argument {
+		case "--add":
+			isAdd = true
+		case "--allowed", "-a":
+			isAllowed = true
+		case "--literal", "-l":
+			isLiteral = true
+		case "--global":
+		default:
+			values = append(values, argument)
+		}
+	}
+
+	if !isAdd {
+		return "", false, errors.New("only \"git secrets --add\" commands can be imported")
+	}
+
+	if len(values) != 1 {
+		return "", false, errors.New("expected a single pattern")
+	}
+
+	expression = values[0]
+	if isLiteral {
+		expression = regexp.QuoteMeta(expression)
+	}
+
+	return expression, isAllowed, nil
+}
+
+// splitShellArguments splits a command into arguments, following single and double quotes and backslash escapes
+func splitShellArguments(command string) ([]string, error) {
+	var arguments []string
+	var current strings.Builder
+	inArgument := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(ch)
+			}
+		case ch == '\\' && i+1 < len(runes) && (quote == 0 || strings.ContainsRune(`"\$`+"`", runes[i+1])):
+			i++
+			current.WriteRune(runes[i])
+			inArgument = true
+		case quote == '"':
+			if ch == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inArgument = true
+		case ch == ' ' || ch == '\t':
+			if inArgument {
+				arguments = append(arguments, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		default:
+			current.WriteRune(ch)
+			inArgument = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if inArgument {
+		arguments = append(arguments, current.String())
+	}
+
+	return arguments, nil
+}
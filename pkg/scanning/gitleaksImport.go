@@ -0,0 +1,161 @@
+package scanning
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"regexp"
+)
+
+// ImportGitleaksRules converts the rules of a gitleaks .toml config into search patterns. Allowlisted regexes and
+// stopwords become exclusions, while allowlisted paths and commits can't be represented and are reported as warnings.
+// Allowlists which need all of several conditions to match are left out, as any one of them would exclude far more.
+func ImportGitleaksRules(data []byte) (*PatternImport, error) {
+	var config map[string]interface{}
+	if _, err := toml.Decode(string(data), &config); err != nil {
+		return nil, err
+	}
+
+	result := &PatternImport{}
+
+	if extend, ok := config["extend"].(map[string]interface{}); ok && len(extend) > 0 {
+		result.warn("the config extends other rules, which are not imported")
+	}
+
+	// The global allowlist applies to every rule
+	var globalExclusions []string
+	for _, allowlist := range gitleaksAllowlists(config) {
+		globalExclusions = append(globalExclusions, convertGitleaksAllowlist("global allowlist", allowlist, result)...)
+	}
+
+	rules, _ := config["rules"].([]map[string]interface{})
+	for i, rule := range rules {
+		id := tomlString(rule, "id")
+		if id == "" {
+			id = fmt.Sprintf("rule %d", i+1)
+		}
+
+		expression := tomlString(rule, "regex")
+		if expression == "" {
+			result.skip(id, "rules without a regex, such as path-only rules, are not supported")
+			continue
+		}
+
+		if secretGroup := tomlInt(rule, "secretGroup"); secretGroup > 0 {
+			named, err := nameSecretGroup(expression, int(secretGroup))
+			if err != nil {
+				result.skip(id, fmt.Sprintf("invalid secretGroup: %v", err))
+				continue
+			}
+			expression = named
+		}
+
+		if tomlString(rule, "path") != "" {
+			result.warn("rule \"%s\" is limited to paths matching %s, but will be applied to every path", id, tomlString(rule, "path"))
+		}
+
+		kind := tomlString(rule, "description")
+		if kind == "" {
+			kind = id
+		}
+
+		pattern := SearchPattern{
+			Pattern:    expression,
+			Kind:       kind,
+			Keywords:   tomlStrings(rule, "keywords"),
+			Entropy:    tomlFloat(rule, "entropy"),
+			Exclusions: append([]string{}, globalExclusions...),
+		}
+
+		for _, allowlist := range gitleaksAllowlists(rule) {
+			pattern.Exclusions = append(pattern.Exclusions, convertGitleaksAllowlist(id, allowlist, result)...)
+		}
+
+		result.add(id, pattern)
+	}
+
+	return result, nil
+}
+
+// gitleaksAllowlists gets the allowlists of a rule or config, which older configs declare as a single table and newer
+// configs as an array of tables
+func gitleaksAllowlists(table map[string]interface{}) []map[string]interface{} {
+	var allowlists []map[string]interface{}
+	if allowlist, ok := table["allowlist"].(map[string]interface{}); ok {
+		allowlists = append(allowlists, allowlist)
+	}
+
+	if arrayOfAllowlists, ok := table["allowlists"].([]map[string]interface{}); ok {
+		allowlists = append(allowlists, arrayOfAllowlists...)
+	}
+
+	return allowlists
+}
+
+func convertGitleaksAllowlist(rule string, allowlist map[string]interface{}, result *PatternImport) []string {
+
+	// Exclusions apply when any of them match, so an allowlist needing several kinds of condition can't be converted
+	conditions := 0
+	for _, key := range []string{"regexes", "stopwords", "paths", "commits"} {
+		if len(tomlStrings(allowlist, key)) > 0 {
+			conditions++
+		}
+	}
+	if tomlString(allowlist, "condition") == "AND" && conditions > 1 {
+		result.warn("the allowlist for %s requires all of its conditions to match, so it was not converted", rule)
+		return nil
+	}
+
+	var exclusions []string
+	exclusions = append(exclusions, tomlStrings(allowlist, "regexes")...)
+	for _, stopword := range tomlStrings(allowlist, "stopwords") {
+		exclusions = append(exclusions, "(?i)"+regexp.QuoteMeta(stopword))
+	}
+
+	if len(tomlStrings(allowlist, "paths")) > 0 {
+		result.warn("allowlisted paths for %s are not supported and have been ignored", rule)
+	}
+
+	if len(tomlStrings(allowlist, "commits")) > 0 {
+		result.warn("allowlisted commits for %s are not supported and have been ignored", rule)
+	}
+
+	if target := tomlString(allowlist, "regexTarget"); target == "line" || target == "match" {
+		result.warn("allowlist regexes for %s target the %s, but will be applied to the matched value", rule, target)
+	}
+
+	return exclusions
+}
+
+func tomlString(table map[string]interface{}, key string) string {
+	value, _ := table[key].(string)
+	return value
+}
+
+func tomlStrings(table map[string]interface{}, key string) []string {
+	values, _ := table[key].([]interface{})
+
+	var result []string
+	for _, value := range values {
+		if stringValue, ok := value.(string); ok {
+			result = append(result, stringValue)
+		}
+	}
+
+	return result
+}
+
+func tomlInt(table map[string]interface{}, key string) int64 {
+	value, _ := table[key].(int64)
+	return value
+}
+
+func tomlFloat(table map[string]interface{}, key string) float64 {
+	switch value := table[key].(type) {
+	case float64:
+		return value
+	case int64:
+		return float64(value)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,243 @@
+package scanning
+
+import (
+	"Orca/pkg/caching"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// HoneytokenKind is the kind of match reported for a honeytoken, which is never filtered out like other matches
+const HoneytokenKind = "Honeytoken"
+
+const honeytokenFingerprintPrefix = "sha256:"
+
+const honeytokenRemediation = "This is a canary credential planted by the security team, and finding it means it has " +
+	"leaked from where it was planted. The security team has been alerted. Don't rotate or remove it yourself."
+
+// Honeytoken is a canary credential. Only a fingerprint of the token is kept, so that the registry doesn't hold the
+// tokens themselves.
+type Honeytoken struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	Description string `json:"description,omitempty"`
+}
+
+// HoneytokenFingerprint returns the fingerprint of a token's value, which is its SHA-256 hash
+func HoneytokenFingerprint(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return honeytokenFingerprintPrefix + hex.EncodeToString(hash[:])
+}
+
+// HoneytokenFingerprint returns the fingerprint of the honeytoken a match found, or an empty string if it isn't one
+func (match *Match) HoneytokenFingerprint() string {
+	if match.Honeytoken == "" {
+		return ""
+	}
+
+	return HoneytokenFingerprint(match.value)
+}
+
+func (honeytoken *Honeytoken) Validate() error {
+	if len(honeytoken.Name) == 0 {
+		return errors.New("honeytokens must have a name")
+	}
+
+	hash := strings.TrimPrefix(honeytoken.Fingerprint, honeytokenFingerprintPrefix)
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size ||
+		!strings.HasPrefix(honeytoken.Fingerprint, honeytokenFingerprintPrefix) {
+		return fmt.Errorf("invalid fingerprint for honeytoken \"%s\", expected sha256:<hex>", honeytoken.Name)
+	}
+
+	return nil
+}
+
+type HoneytokenStore interface {
+	GetHoneytokens() ([]Honeytoken, error)
+}
+
+type FileHoneytokenStore struct {
+	HoneytokensJsonFile string
+}
+
+func (store *FileHoneytokenStore) GetHoneytokens() ([]Honeytoken, error) {
+	data, err := ioutil.ReadFile(store.HoneytokensJsonFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHoneytokens(data)
+}
+
+// NewHoneytokenStore creates a store for the honeytoken registry at a location
+func NewHoneytokenStore(honeytokensLocation string) (HoneytokenStore, error) {
+	if strings.HasPrefix(honeytokensLocation, "http") {
+		// Todo
+		return nil, errors.New("fetching honeytokens from a URL is not yet implemented")
+	} else if !fileExists(honeytokensLocation) {
+		return nil, fmt.Errorf("unsupported honeytokens location \"%s\"", honeytokensLocation)
+	}
+
+	store := &FileHoneytokenStore{HoneytokensJsonFile: honeytokensLocation}
+
+	// Refuse to start with a registry which can't be read, rather than silently missing leaked honeytokens
+	if _, err := store.GetHoneytokens(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func parseHoneytokens(data []byte) ([]Honeytoken, error) {
+	var result []Honeytoken
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	for _, honeytoken := range result {
+		if err := honeytoken.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// honeytokenCandidateRegex finds the words which could be tokens, separated by whitespace, quotes and brackets
+var honeytokenCandidateRegex = regexp.MustCompile("[^\\s\"'`,;()\\[\\]{}<>]+")
+
+// honeytokensByFingerprint indexes the registry by fingerprint, which are compared in lower case
+func honeytokensByFingerprint(honeytokens []Honeytoken) map[string]Honeytoken {
+	result := map[string]Honeytoken{}
+	for _, honeytoken := range honeytokens {
+		result[strings.ToLower(honeytoken.Fingerprint)] = honeytoken
+	}
+
+	return result
+}
+
+// findHoneytokens checks every word on a line against the registry's fingerprints. The value after an = or : in a
+// word is checked too, so that e.g. TOKEN=value and password:value are found, as are values followed by a full stop.
+func findHoneytokens(line string, honeytokens map[string]Honeytoken) []Match {
+	if len(honeytokens) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for _, word := range honeytokenCandidateRegex.FindAllStringIndex(line, -1) {
+		for start := word[0]; start < word[1]; start++ {
+			if start != word[0] && line[start-1] != '=' && line[start-1] != ':' {
+				continue
+			}
+
+			for _, end := range []int{word[1], word[1] - 1} {
+				if end <= start || (end != word[1] && line[end] != '.') {
+					continue
+				}
+
+				honeytoken, ok := honeytokens[HoneytokenFingerprint(line[start:end])]
+				if !ok {
+					continue
+				}
+
+				startColumn := utf8.RuneCountInString(line[:start])
+				matches = append(matches, Match{
+					StartIndex:  start,
+					EndIndex:    end,
+					StartColumn: startColumn,
+					EndColumn:   startColumn + utf8.RuneCountInString(line[start:end]),
+					value:       line[start:end],
+					Kind:        HoneytokenKind,
+					Severity:    SeverityCritical,
+					Remediation: honeytokenRemediation,
+					Honeytoken:  honeytoken.Name,
+					specificity: maxSpecificity,
+				})
+				start = end
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// withHoneytokens adds honeytoken matches to a line's other matches. Other matches of the same value are folded into
+// the honeytoken match, which is always reported however the other matches were filtered.
+func withHoneytokens(matches []Match, honeytokenMatches []Match) []Match {
+	if len(honeytokenMatches) == 0 {
+		return matches
+	}
+
+	var result []Match
+	for _, match := range matches {
+		overlapped := false
+		for i := range honeytokenMatches {
+			honeytokenMatch := &honeytokenMatches[i]
+			if match.StartIndex < honeytokenMatch.EndIndex && honeytokenMatch.StartIndex < match.EndIndex {
+				overlapped = true
+				if !containsString(honeytokenMatch.SecondaryKinds, match.Kind) {
+					honeytokenMatch.SecondaryKinds = append(honeytokenMatch.SecondaryKinds, match.Kind)
+				}
+			}
+		}
+
+		if !overlapped {
+			result = append(result, match)
+		}
+	}
+
+	result = append(result, honeytokenMatches...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartIndex < result[j].StartIndex
+	})
+
+	return result
+}
+
+// checkSkippedFileForHoneytokens looks for honeytokens in a file which isn't otherwise scanned. Files can be skipped
+// because of a repository's .gitattributes, which mustn't be a way to hide a honeytoken.
+func (scanner *Scanner) checkSkippedFileForHoneytokens(file *caching.File, lines []contentLine) []FileContentMatch {
+	honeytokens := honeytokensByFingerprint(scanner.Honeytokens)
+
+	var matches []FileContentMatch
+	for _, line := range lines {
+		for _, match := range findHoneytokens(strings.TrimSuffix(line.text, "\r"), honeytokens) {
+			matches = append(matches, FileContentMatch{File: *file, LineMatch: scanner.newLineMatch(line.number, match)})
+		}
+	}
+
+	return matches
+}
+
+// HasHoneytokens checks if any of the matches are honeytokens
+func HasHoneytokens(matches []LineMatch) bool {
+	for _, match := range matches {
+		if match.Honeytoken != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HoneytokenMatches returns the honeytoken matches from a push or pull request's commits
+func HoneytokenMatches(results []CommitScanResult) []FileContentMatch {
+	var matches []FileContentMatch
+	for _, result := range results {
+		for _, match := range result.Matches {
+			if match.Honeytoken != "" {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	return matches
+}
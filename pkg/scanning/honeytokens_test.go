@@ -0,0 +1,96 @@
+package scanning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHoneytokens(t *testing.T) {
+	canary := "AKIAZQ3DR5CANARY0001"
+	honeytokens := []Honeytoken{{Name: "aws-canary", Fingerprint: HoneytokenFingerprint(canary)}}
+
+	tests := []struct {
+		name         string
+		path         string
+		content      string
+		placeholders PlaceholderOptions
+		kinds        []string
+		secondary    []string
+	}{
+		{
+			name:      "folds other matches into the honeytoken",
+			content:   "aws_access_key_id = " + canary,
+			kinds:     []string{HoneytokenKind},
+			secondary: []string{"AWS Key"},
+		},
+		{
+			name:      "after an equals sign and before a full stop",
+			content:   "export TOKEN=" + canary + ".",
+			kinds:     []string{HoneytokenKind},
+			secondary: []string{"AWS Key"},
+		},
+		{
+			name:         "can't be suppressed as a placeholder",
+			content:      "key: " + canary,
+			placeholders: PlaceholderOptions{Words: []string{canary}},
+			kinds:        []string{HoneytokenKind},
+		},
+		{
+			name:    "in a comment in code",
+			path:    "main.go",
+			content: "// " + canary,
+			kinds:   []string{HoneytokenKind},
+		},
+		{
+			name:    "part of a longer value",
+			content: "key: " + canary + "X",
+			kinds:   []string{"AWS Key"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{
+				Patterns:     []SearchPattern{{Pattern: `AKIA[0-9A-Z]{16}`, Kind: "AWS Key", Scope: PatternScopeCode}},
+				Placeholders: test.placeholders,
+				Honeytokens:  honeytokens,
+			}
+
+			result, err := scanner.ScanFileContent(test.path, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var kinds []string
+			var secondary []string
+			for _, match := range result.Matches {
+				kinds = append(kinds, match.Kind)
+				secondary = append(secondary, match.SecondaryKinds...)
+				if match.Kind == HoneytokenKind && match.Honeytoken != "aws-canary" {
+					t.Errorf("expected the honeytoken's name but got %q", match.Honeytoken)
+				}
+			}
+
+			if !reflect.DeepEqual(kinds, test.kinds) || !reflect.DeepEqual(secondary, test.secondary) {
+				t.Errorf("expected %v %v but got %v %v", test.kinds, test.secondary, kinds, secondary)
+			}
+		})
+	}
+}
+
+func TestParseHoneytokens(t *testing.T) {
+	valid := `[{"name": "canary", "fingerprint": "` + HoneytokenFingerprint("token") + `"}]`
+	if _, err := parseHoneytokens([]byte(valid)); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	for _, invalid := range []string{
+		`[{"fingerprint": "` + HoneytokenFingerprint("token") + `"}]`,
+		`[{"name": "canary", "fingerprint": "token"}]`,
+		`[{"name": "canary", "fingerprint": "sha256:abcd"}]`,
+	} {
+		if _, err := parseHoneytokens([]byte(invalid)); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package scanning
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func isTerraformState(filePath string) bool {
+	name := path.Base(filePath)
+	return strings.HasSuffix(name, ".tfstate") || strings.HasSuffix(name, ".tfstate.backup")
+}
+
+type terraformOutput struct {
+	Value     interface{} `json:"value"`
+	Sensitive bool        `json:"sensitive"`
+}
+
+type terraformState struct {
+	Outputs   map[string]terraformOutput `json:"outputs"`
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			Attributes          map[string]interface{} `json:"attributes"`
+			SensitiveAttributes []json.RawMessage      `json:"sensitive_attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+var terraformStateDetector = builtInDetector{
+	name:     "terraform-state",
+	severity: SeverityCritical,
+	remediation: "Remove the state file from the repository and keep state in a remote backend with encryption and " +
+		"access control. State holds every attribute in plain text, so rotate the sensitive values it contains.",
+	detect: func(input *detectorInput) []detection {
+		if !isTerraformState(input.path) || len(input.lines) == 0 {
+			return nil
+		}
+
+		// Committed state is always reported, even if none of its values look sensitive
+		result := []detection{newDetection(0, 0, len(input.lines[0]), "Terraform state file")}
+
+		var state terraformState
+		if err := json.Unmarshal([]byte(strings.Join(input.lines, "\n")), &state); err != nil {
+			return result
+		}
+
+		locator := &jsonValueLocator{lines: input.lines}
+		for _, resource := range state.Resources {
+			address := resource.Type + "." + resource.Name
+			if resource.Mode == "data" {
+				address = "data." + address
+			}
+			if resource.Module != "" {
+				address = resource.Module + "." + address
+			}
+
+			for _, instance := range resource.Instances {
+				sensitive := terraformSensitiveAttributes(instance.SensitiveAttributes)
+				for _, name := range sortedKeys(instance.Attributes) {
+					value, ok := instance.Attributes[name].(string)
+					if !ok || value == "" || (!sensitive[name] && !isCredentialKey(name)) {
+						continue
+					}
+
+					if found, ok := locator.find(name, value); ok {
+						found.kind = "Terraform state sensitive value"
+						found.location = address + "." + name
+						result = append(result, found)
+					}
+				}
+			}
+		}
+
+		var outputNames []string
+		for name := range state.Outputs {
+			outputNames = append(outputNames, name)
+		}
+		sort.Strings(outputNames)
+
+		for _, name := range outputNames {
+			output := state.Outputs[name]
+			value, ok := output.Value.(string)
+			if !ok || value == "" || (!output.Sensitive && !isCredentialKey(name)) {
+				continue
+			}
+
+			if found, ok := locator.find("value", value); ok {
+				found.kind = "Terraform state sensitive value"
+				found.location = "output." + name
+				result = append(result, found)
+			}
+		}
+
+		return result
+	},
+}
+
+// terraformSensitiveAttributes returns the top level attributes named in a state's sensitive_attributes, which is a
+// list of paths, each a list of steps such as {"type": "get_attr", "value": "password"}
+func terraformSensitiveAttributes(paths []json.RawMessage) map[string]bool {
+	result := map[string]bool{}
+	for _, raw := range paths {
+		var steps []struct {
+			Type  string      `json:"type"`
+			Value interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(raw, &steps); err != nil || len(steps) == 0 {
+			continue
+		}
+
+		if name, ok := steps[0].Value.(string); ok && steps[0].Type == "get_attr" {
+			result[name] = true
+		}
+	}
+
+	return result
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// jsonValueLocator finds the line a string value was on in a JSON document, as encoding/json doesn't keep positions.
+// Each value is only found once, so that repeated values are matched to successive lines.
+type jsonValueLocator struct {
+	lines []string
+	used  map[detection]bool
+}
+
+func (locator *jsonValueLocator) find(key string, value string) (detection, bool) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return detection{}, false
+	}
+
+	if locator.used == nil {
+		locator.used = map[detection]bool{}
+	}
+
+	regex := regexp.MustCompile(`"` + regexp.QuoteMeta(key) + `"\s*:\s*(` + regexp.QuoteMeta(string(encoded)) + `)`)
+	for i, line := range locator.lines {
+		for _, match := range regex.FindAllStringSubmatchIndex(line, -1) {
+
+			// The value is reported without its quotes
+			found := newDetection(i, match[2]+1, match[3]-1, "")
+			if !locator.used[found] {
+				locator.used[found] = true
+				return found, true
+			}
+		}
+	}
+
+	return detection{}, false
+}
+
+var (
+	terraformBlockRegex      = regexp.MustCompile(`^\s*variable\s+"([^"]+)"\s*\{`)
+	terraformAssignmentRegex = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_-]*)\s*=\s*`)
+)
+
+var terraformVariablesDetector = builtInDetector{
+	name:     "terraform-variables",
+	severity: SeverityHigh,
+	remediation: "Remove the literal value and supply it from a secret store or a TF_VAR_ environment variable in the " +
+		"pipeline instead, and rotate it.",
+	detect: func(input *detectorInput) []detection {
+		name := path.Base(input.path)
+		switch {
+		case strings.HasSuffix(name, ".tf"):
+			return detectSensitiveVariableDefaults(input.lines)
+		case strings.HasSuffix(name, ".tfvars"):
+			return detectTerraformVariableValues(input.lines)
+		default:
+			return nil
+		}
+	},
+}
+
+// detectSensitiveVariableDefaults finds variables marked with sensitive = true which have a literal default
+func detectSensitiveVariableDefaults(lines []string) []detection {
+	var result []detection
+	variable := ""
+	depth := 0
+	sensitive := false
+	var defaultValue *detection
+	for i, line := range lines {
+		if variable == "" {
+			if match := terraformBlockRegex.FindStringSubmatch(line); match != nil {
+				variable = match[1]
+				depth = 0
+				sensitive = false
+				defaultValue = nil
+			} else {
+				continue
+			}
+		}
+
+		// Only assignments directly within the variable block count
+		if depth == 1 {
+			if match := terraformAssignmentRegex.FindStringSubmatchIndex(line); match != nil {
+				switch line[match[2]:match[3]] {
+				case "sensitive":
+					sensitive = strings.HasPrefix(strings.TrimSpace(line[match[1]:]), "true")
+				case "default":
+					if start, end, ok := hclStringSpan(line, match[1]); ok {
+						found := newDetection(i, start, end, "Terraform sensitive variable default")
+						found.location = "var." + variable
+						defaultValue = &found
+					}
+				}
+			}
+		}
+
+		depth += hclBraceDepthChange(line)
+		if depth <= 0 {
+			if sensitive && defaultValue != nil &&
+				isLiteralValue(lines[defaultValue.lineIndex][defaultValue.startIndex:defaultValue.endIndex]) {
+				result = append(result, *defaultValue)
+			}
+			variable = ""
+		}
+	}
+
+	return result
+}
+
+// detectTerraformVariableValues finds credentials assigned to variables in a tfvars file. Whether a variable is marked
+// as sensitive is only known from where it is declared, so variables are judged by their names.
+func detectTerraformVariableValues(lines []string) []detection {
+	var result []detection
+	depth := 0
+	for i, line := range lines {
+		if depth == 0 {
+			if match := terraformAssignmentRegex.FindStringSubmatchIndex(line); match != nil {
+				name := line[match[2]:match[3]]
+				if start, end, ok := hclStringSpan(line, match[1]); ok && isCredentialKey(name) &&
+					isLiteralValue(line[start:end]) {
+					found := newDetection(i, start, end, "Terraform variable value")
+					found.location = "var." + name
+					result = append(result, found)
+				}
+			}
+		}
+
+		depth += hclBraceDepthChange(line)
+	}
+
+	return result
+}
+
+// hclStringSpan returns the contents of the quoted string starting at offset, if there is one
+func hclStringSpan(line string, offset int) (int, int, bool) {
+	if offset >= len(line) || line[offset] != '"' {
+		return 0, 0, false
+	}
+
+	for i := offset + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return offset + 1, i, i > offset+1
+		}
+	}
+
+	return 0, 0, false
+}
+
+// hclBraceDepthChange counts how a line changes the nesting of blocks and objects, ignoring braces in strings and
+// comments
+func hclBraceDepthChange(line string) int {
+	change := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case inString && ch == '\\':
+			i++
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '#' || strings.HasPrefix(line[i:], "//"):
+			return change
+		case ch == '{' || ch == '[':
+			change++
+		case ch == '}' || ch == ']':
+			change--
+		}
+	}
+
+	return change
+}
+
+type cloudFormationTemplate struct {
+	AWSTemplateFormatVersion interface{}                       `json:"AWSTemplateFormatVersion"`
+	Parameters               map[string]map[string]interface{} `json:"Parameters"`
+}
+
+var cloudFormationNoEchoDetector = builtInDetector{
+	name:     "cloudformation-noecho",
+	severity: SeverityHigh,
+	remediation: "Remove the default from the NoEcho parameter and pass the value in when deploying the stack, or use a " +
+		"dynamic reference to Secrets Manager or Parameter Store, and rotate it.",
+	detect: func(input *detectorInput) []detection {
+		var result []detection
+
+		// Templates can be YAML
+		for _, document := range input.yaml() {
+			parameters := document.Get("Parameters")
+			if parameters == nil || (document.Get("Resources") == nil && document.Get("AWSTemplateFormatVersion") == nil) {
+				continue
+			}
+
+			for i, name := range parameters.Keys {
+				parameter := parameters.Values[i]
+				defaultValue := parameter.Get("Default")
+				if !strings.EqualFold(parameter.GetValue("NoEcho"), "true") || defaultValue == nil ||
+					defaultValue.Start == defaultValue.End || !isLiteralValue(defaultValue.Value) {
+					continue
+				}
+
+				found := newDetection(
+					defaultValue.Line-1,
+					defaultValue.Start,
+					defaultValue.End,
+					"CloudFormation NoEcho default")
+				found.location = fmt.Sprintf("Parameters.%s.Default", name)
+				result = append(result, found)
+			}
+		}
+
+		// Or JSON
+		extension := strings.ToLower(path.Ext(input.path))
+		if extension != ".json" && extension != ".template" {
+			return result
+		}
+
+		var template cloudFormationTemplate
+		if err := json.Unmarshal([]byte(strings.Join(input.lines, "\n")), &template); err != nil ||
+			template.AWSTemplateFormatVersion == nil {
+			return result
+		}
+
+		locator := &jsonValueLocator{lines: input.lines}
+		var names []string
+		for name := range template.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			parameter := template.Parameters[name]
+			defaultValue, ok := parameter["Default"].(string)
+			if !ok || !isLiteralValue(defaultValue) || !strings.EqualFold(fmt.Sprint(parameter["NoEcho"]), "true") {
+				continue
+			}
+
+			if found, ok := locator.find("Default", defaultValue); ok {
+				found.kind = "CloudFormation NoEcho default"
+				found.location = fmt.Sprintf("Parameters.%s.Default", name)
+				result = append(result, found)
+			}
+		}
+
+		return result
+	},
+}
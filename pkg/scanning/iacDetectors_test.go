@@ -0,0 +1,147 @@
+package scanning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIacDetectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		content   string
+		locations []string
+		values    []string
+	}{
+		{
+			name: "terraform state",
+			path: "infra/terraform.tfstate",
+			content: `{
+  "version": 4,
+  "outputs": {
+    "db_endpoint": {"value": "db.internal", "type": "string"},
+    "admin_key": {"value": "k3y-0utput", "type": "string", "sensitive": true}
+  },
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_db_instance",
+      "name": "main",
+      "instances": [
+        {
+          "attributes": {
+            "identifier": "orca",
+            "master_password": "hunter2-db",
+            "port": 5432
+          },
+          "sensitive_attributes": []
+        }
+      ]
+    },
+    {
+      "module": "module.cache",
+      "mode": "managed",
+      "type": "random_string",
+      "name": "auth",
+      "instances": [
+        {
+          "attributes": {"id": "x", "result": "r4nd0m-auth"},
+          "sensitive_attributes": [[{"type": "get_attr", "value": "result"}]]
+        }
+      ]
+    }
+  ]
+}`,
+			locations: []string{
+				"",
+				"output.admin_key",
+				"aws_db_instance.main.master_password",
+				"module.cache.random_string.auth.result",
+			},
+			values: []string{"{", "k3y-0utput", "hunter2-db", "r4nd0m-auth"},
+		},
+		{
+			name: "sensitive variable default",
+			path: "variables.tf",
+			content: `variable "region" {
+  default = "eu-west-1"
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+  default   = "hunter2-tf"
+
+  validation {
+    condition = length(var.db_password) > 8
+  }
+}
+
+variable "api_token" {
+  sensitive = true
+}`,
+			locations: []string{"var.db_password"},
+			values:    []string{"hunter2-tf"},
+		},
+		{
+			name: "tfvars",
+			path: "prod.tfvars",
+			content: `region      = "eu-west-1"
+db_password = "hunter2-vars"
+tags = {
+  token = "not-a-variable"
+}`,
+			locations: []string{"var.db_password"},
+			values:    []string{"hunter2-vars"},
+		},
+		{
+			name: "cloudformation yaml",
+			path: "stack.yaml",
+			content: `AWSTemplateFormatVersion: "2010-09-09"
+Parameters:
+  DbPassword:
+    Type: String
+    NoEcho: true
+    Default: hunter2-cfn
+  DbUser:
+    Type: String
+    Default: orca
+Resources: {}`,
+			locations: []string{"Parameters.DbPassword.Default"},
+			values:    []string{"hunter2-cfn"},
+		},
+		{
+			name: "cloudformation json",
+			path: "stack.json",
+			content: `{
+  "AWSTemplateFormatVersion": "2010-09-09",
+  "Parameters": {
+    "DbPassword": {"Type": "String", "NoEcho": "true", "Default": "hunter2-json"}
+  }
+}`,
+			locations: []string{"Parameters.DbPassword.Default"},
+			values:    []string{"hunter2-json"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{}
+			result, err := scanner.ScanFileContent(test.path, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var locations []string
+			var values []string
+			for _, match := range result.Matches {
+				locations = append(locations, match.Location)
+				values = append(values, match.value)
+			}
+
+			if !reflect.DeepEqual(locations, test.locations) || !reflect.DeepEqual(values, test.values) {
+				t.Errorf("expected %v %v but got %v %v", test.locations, test.values, locations, values)
+			}
+		})
+	}
+}
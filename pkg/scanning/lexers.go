@@ -0,0 +1,366 @@
+package scanning
+
+import (
+	"path"
+	"strings"
+)
+
+// Pattern scopes
+const (
+	PatternScopeCode     = "code"
+	PatternScopeAnywhere = "anywhere"
+)
+
+// stringSyntax describes a kind of string literal. Strings which can't span lines end at the end of the line, so that
+// a missing quote doesn't hide the rest of the file.
+type stringSyntax struct {
+	open      string
+	close     string
+	escape    bool
+	multiLine bool
+}
+
+// languageSyntax holds enough of a language's syntax to find its comments, string literals and assignments
+type languageSyntax struct {
+	name          string
+	extensions    []string
+	lineComments  []string
+	blockComments [][2]string
+
+	// Strings are checked in order, so longer openings such as """ must come before "
+	strings []stringSyntax
+}
+
+var (
+	doubleQuotedString = stringSyntax{open: `"`, close: `"`, escape: true}
+	singleQuotedString = stringSyntax{open: `'`, close: `'`, escape: true}
+	cComments          = [][2]string{{"/*", "*/"}}
+)
+
+var languageSyntaxes = []languageSyntax{
+	{
+		name:          "go",
+		extensions:    []string{".go"},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuotedString, singleQuotedString, {open: "`", close: "`", multiLine: true}},
+	},
+	{
+		name:          "csharp",
+		extensions:    []string{".cs"},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, multiLine: true},
+			{open: `@"`, close: `"`, multiLine: true},
+			{open: `$@"`, close: `"`, multiLine: true},
+			doubleQuotedString,
+			singleQuotedString,
+		},
+	},
+	{
+		name:          "java",
+		extensions:    []string{".java", ".kt", ".kts", ".scala", ".groovy", ".gradle"},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, escape: true, multiLine: true},
+			doubleQuotedString,
+			singleQuotedString,
+		},
+	},
+	{
+		name:          "javascript",
+		extensions:    []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings: []stringSyntax{
+			doubleQuotedString,
+			singleQuotedString,
+			{open: "`", close: "`", escape: true, multiLine: true},
+		},
+	},
+	{
+		name:         "python",
+		extensions:   []string{".py"},
+		lineComments: []string{"#"},
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, escape: true, multiLine: true},
+			{open: `'''`, close: `'''`, escape: true, multiLine: true},
+			doubleQuotedString,
+			singleQuotedString,
+		},
+	},
+	{
+		name:         "ruby",
+		extensions:   []string{".rb"},
+		lineComments: []string{"#"},
+		strings:      []stringSyntax{doubleQuotedString, singleQuotedString},
+	},
+	{
+		name:          "php",
+		extensions:    []string{".php"},
+		lineComments:  []string{"//", "#"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuotedString, singleQuotedString},
+	},
+	{
+		name:          "c",
+		extensions:    []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".m", ".swift", ".rs"},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuotedString, singleQuotedString},
+	},
+}
+
+// languageForPath returns the syntax of a source file, or nil if its language doesn't have a lexer
+func languageForPath(filePath string) *languageSyntax {
+	extension := strings.ToLower(path.Ext(filePath))
+	for i, language := range languageSyntaxes {
+		for _, languageExtension := range language.extensions {
+			if extension == languageExtension {
+				return &languageSyntaxes[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+type codeRegionKind int
+
+const (
+	stringLiteralRegion codeRegionKind = iota
+	assignmentRegion
+)
+
+// codeRegion is part of a line which can hold a secret: a string literal, or the right hand side of an assignment.
+// assignedTo is the name of the variable, field or key being assigned to, if there is one.
+type codeRegion struct {
+	kind       codeRegionKind
+	startIndex int
+	endIndex   int
+	assignedTo string
+}
+
+// describe explains where a value was found, e.g. "string assigned to apiKey"
+func (region *codeRegion) describe() string {
+	switch {
+	case region.kind == stringLiteralRegion && region.assignedTo != "":
+		return "string assigned to " + region.assignedTo
+	case region.kind == stringLiteralRegion:
+		return "string literal"
+	case region.assignedTo != "":
+		return "value assigned to " + region.assignedTo
+	default:
+		return "assigned value"
+	}
+}
+
+// codeLexer finds the regions of each line which can hold secrets. It is not a full tokeniser: it only tracks
+// comments, strings, identifiers and the operators which start and end assignments.
+type codeLexer struct {
+	syntax *languageSyntax
+
+	// State carried between lines by block comments and multi-line strings
+	blockCommentClose string
+	openString        *stringSyntax
+	stringAssignedTo  string
+
+	// State within a statement
+	lastIdentifier string
+	lastString     string
+	assignedTo     string
+	assignment     *codeRegion
+}
+
+func (lexer *codeLexer) lexLine(line string) []codeRegion {
+	var regions []codeRegion
+	lexer.endStatement(&regions, len(line))
+
+	for i := 0; i < len(line); {
+		rest := line[i:]
+
+		if lexer.blockCommentClose != "" {
+			end := strings.Index(rest, lexer.blockCommentClose)
+			if end < 0 {
+				return regions
+			}
+			i += end + len(lexer.blockCommentClose)
+			lexer.blockCommentClose = ""
+			continue
+		}
+
+		if lexer.openString != nil {
+			end, closed := lexer.findStringClose(line, i)
+			regions = append(regions, codeRegion{
+				kind:       stringLiteralRegion,
+				startIndex: i,
+				endIndex:   end,
+				assignedTo: lexer.stringAssignedTo,
+			})
+			lexer.lastString = line[i:end]
+			if !closed {
+				if !lexer.openString.multiLine {
+					lexer.openString = nil
+				}
+				return regions
+			}
+			i = end + len(lexer.openString.close)
+			lexer.openString = nil
+			continue
+		}
+
+		if hasAnyPrefix(rest, lexer.syntax.lineComments) {
+			lexer.endStatement(&regions, i)
+			return regions
+		}
+
+		if close := lexer.blockCommentFor(rest); close != "" {
+			lexer.blockCommentClose = close
+			i += 2
+			continue
+		}
+
+		if syntax := lexer.stringFor(rest); syntax != nil {
+			lexer.openString = syntax
+			lexer.stringAssignedTo = lexer.assignedTo
+			lexer.lastIdentifier = ""
+			i += len(syntax.open)
+			continue
+		}
+
+		ch := line[i]
+		switch {
+		case isIdentifierCharacter(ch):
+			start := i
+			for i < len(line) && (isIdentifierCharacter(line[i]) || line[i] == '.') {
+				i++
+			}
+			identifier := line[start:i]
+			if dot := strings.LastIndexByte(identifier, '.'); dot >= 0 {
+				identifier = identifier[dot+1:]
+			}
+			lexer.lastIdentifier = identifier
+			lexer.lastString = ""
+			continue
+		case strings.HasPrefix(rest, "==") || strings.HasPrefix(rest, "!=") || strings.HasPrefix(rest, "<=") ||
+			strings.HasPrefix(rest, ">=") || strings.HasPrefix(rest, "=>"):
+			i += 2
+			continue
+		case strings.HasPrefix(rest, ":="):
+			i++
+			lexer.startAssignment(i, false)
+		case ch == '=' || ch == ':':
+			lexer.startAssignment(i, ch == ':')
+		case ch == ';' || ch == ',' || ch == '{' || ch == '}' || ch == '(' || ch == ')' || ch == '[' || ch == ']':
+			lexer.endStatement(&regions, i)
+		}
+
+		i++
+	}
+
+	lexer.endStatement(&regions, len(line))
+
+	return regions
+}
+
+// startAssignment records the target of an assignment, which is the identifier or string before the operator ending at
+// index. A colon can also start a type annotation, so a later = keeps the target found by the colon.
+func (lexer *codeLexer) startAssignment(index int, isColon bool) {
+	target := lexer.lastIdentifier
+	if lexer.lastString != "" {
+		target = lexer.lastString
+	}
+
+	if !isColon && lexer.assignment != nil && lexer.assignedTo != "" {
+		target = lexer.assignedTo
+	}
+
+	lexer.assignedTo = target
+	lexer.assignment = &codeRegion{kind: assignmentRegion, startIndex: index + 1, assignedTo: target}
+	lexer.lastIdentifier = ""
+	lexer.lastString = ""
+}
+
+// endStatement finishes any assignment at the end of a statement, adding the right hand side as a region
+func (lexer *codeLexer) endStatement(regions *[]codeRegion, index int) {
+	if lexer.assignment != nil && lexer.assignment.startIndex < index {
+		lexer.assignment.endIndex = index
+		*regions = append(*regions, *lexer.assignment)
+	}
+
+	lexer.assignment = nil
+	lexer.assignedTo = ""
+	lexer.lastIdentifier = ""
+	lexer.lastString = ""
+}
+
+// findStringClose finds the end of the open string from index, and whether it closes on this line
+func (lexer *codeLexer) findStringClose(line string, index int) (int, bool) {
+	for i := index; i < len(line); i++ {
+		if lexer.openString.escape && line[i] == '\\' {
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(line[i:], lexer.openString.close) {
+			return i, true
+		}
+	}
+
+	return len(line), false
+}
+
+func (lexer *codeLexer) blockCommentFor(rest string) string {
+	for _, comment := range lexer.syntax.blockComments {
+		if strings.HasPrefix(rest, comment[0]) {
+			return comment[1]
+		}
+	}
+
+	return ""
+}
+
+func (lexer *codeLexer) stringFor(rest string) *stringSyntax {
+	for i, syntax := range lexer.syntax.strings {
+		if strings.HasPrefix(rest, syntax.open) {
+			return &lexer.syntax.strings[i]
+		}
+	}
+
+	return nil
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isIdentifierCharacter(ch byte) bool {
+	return ch == '_' || ch == '$' || ch == '@' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9') || ch >= 0x80
+}
+
+// codeRegionAt returns the region of a line which a match overlaps, preferring string literals, or nil if the match is
+// outside of any string or assignment
+func codeRegionAt(regions []codeRegion, startIndex int, endIndex int) *codeRegion {
+	var result *codeRegion
+	for i, region := range regions {
+		if startIndex < region.endIndex && region.startIndex < endIndex {
+			if region.kind == stringLiteralRegion {
+				return &regions[i]
+			}
+			if result == nil {
+				result = &regions[i]
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,93 @@
+package scanning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeScope(t *testing.T) {
+	patterns := []SearchPattern{
+		{Pattern: `hunter2`, Kind: "Password", Scope: PatternScopeCode},
+		{Pattern: `sk_live_\w+`, Kind: "Stripe key"},
+		{Pattern: `rk_live_\w+`, Kind: "Stripe restricted key", Scope: PatternScopeAnywhere},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		kinds    []string
+		contexts []string
+	}{
+		{
+			name: "go",
+			path: "main.go",
+			content: "// hunter2 is the old password\n" +
+				"func TestHunter2() {}\n" +
+				"func hunter2Rotation() {\n" +
+				"\tpassword := \"hunter2\"\n" +
+				"\tconfig.ApiKey = `multi\n" +
+				"line hunter2`\n" +
+				"}\n",
+			kinds:    []string{"Password", "Password"},
+			contexts: []string{"string assigned to password", "string assigned to ApiKey"},
+		},
+		{
+			name: "python",
+			path: "settings.py",
+			content: "# hunter2\n" +
+				"\"\"\"\n" +
+				"docs hunter2\n" +
+				"\"\"\"\n" +
+				"connect(password='hunter2')\n" +
+				"CONFIG = {\"token\": \"hunter2\"}\n" +
+				"def hunter2(): pass\n",
+			kinds:    []string{"Password", "Password", "Password"},
+			contexts: []string{"string literal", "string assigned to password", "string assigned to token"},
+		},
+		{
+			name: "csharp comparison and block comment",
+			path: "Auth.cs",
+			content: "/* hunter2\n" +
+				"   hunter2 */ var key = @\"hunter2\";\n" +
+				"if (input == \"hunter2\") { }\n",
+			kinds:    []string{"Password", "Password"},
+			contexts: []string{"string assigned to key", "string literal"},
+		},
+		{
+			name:     "default and anywhere scopes",
+			path:     "app.js",
+			content:  "// sk_live_4eC39HqLyjWDarjtT1 hunter2 rk_live_9fB28GpKxiVCzqisS0\n",
+			kinds:    []string{"Stripe key", "Stripe restricted key"},
+			contexts: []string{"", ""},
+		},
+		{
+			name:     "language without a lexer",
+			path:     "notes.txt",
+			content:  "// hunter2\n",
+			kinds:    []string{"Password"},
+			contexts: []string{""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{Patterns: patterns, Detectors: DetectorOptions{Disabled: true}}
+			result, err := scanner.ScanFileContent(test.path, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var kinds []string
+			var contexts []string
+			for _, match := range result.Matches {
+				kinds = append(kinds, match.Kind)
+				contexts = append(contexts, match.CodeContext)
+			}
+
+			if !reflect.DeepEqual(kinds, test.kinds) || !reflect.DeepEqual(contexts, test.contexts) {
+				t.Errorf("expected %v %v but got %v %v", test.kinds, test.contexts, kinds, contexts)
+			}
+		})
+	}
+}
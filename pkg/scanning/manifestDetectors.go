@@ -0,0 +1,326 @@
+package scanning
+
+import (
+	"Orca/pkg/yaml"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// credentialKeyRegex matches the names of settings which hold credentials, and credentialKeyExclusionRegex matches
+// names which only refer to a credential, e.g. DB_PASSWORD_FILE or existingSecret
+var (
+	credentialKeyRegex = regexp.MustCompile(
+		`(?i)pass(?:word|wd|phrase)?|pwd|secret|token|api[_.-]?key|access[_.-]?key|private[_.-]?key|auth[_.-]?key|` +
+			`credentials?|connection[_.-]?string`)
+	credentialKeyExclusionRegex = regexp.MustCompile(
+		`(?i)(?:file|path|dir|ref|name|url|uri|length|type|mode|enabled|id|expiry|ttl)$|^existing`)
+)
+
+func isCredentialKey(key string) bool {
+	return credentialKeyRegex.MatchString(key) && !credentialKeyExclusionRegex.MatchString(key)
+}
+
+// isLiteralValue checks if a setting's value could be a credential rather than being empty or a flag
+func isLiteralValue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "~", "null", "true", "false", "yes", "no", "on", "off":
+		return false
+	default:
+		return true
+	}
+}
+
+func isDockerfile(filePath string) bool {
+	name := path.Base(filePath)
+	return name == "Dockerfile" || name == "Containerfile" || strings.HasPrefix(name, "Dockerfile.") ||
+		strings.HasSuffix(strings.ToLower(name), ".dockerfile")
+}
+
+var dockerfileDetector = builtInDetector{
+	name:     "dockerfile",
+	severity: SeverityHigh,
+	remediation: "Remove the credential from the Dockerfile, as ENV and ARG values are kept in the image's history, and " +
+		"pass it in at runtime or with a build secret (RUN --mount=type=secret) instead.",
+	detect: func(input *detectorInput) []detection {
+		if !isDockerfile(input.path) {
+			return nil
+		}
+
+		var result []detection
+		instruction := ""
+		for i, line := range input.lines {
+			tokens := splitDockerfileTokens(line)
+
+			// Instructions can continue onto the next line with a backslash
+			continues := len(tokens) > 0 && line[tokens[len(tokens)-1][0]:tokens[len(tokens)-1][1]] == "\\"
+			if continues {
+				tokens = tokens[:len(tokens)-1]
+			}
+
+			if instruction == "" && len(tokens) > 0 {
+				word := strings.ToUpper(line[tokens[0][0]:tokens[0][1]])
+				if word == "ENV" || word == "ARG" {
+					instruction = word
+					tokens = tokens[1:]
+
+					// ENV KEY value is the older form, where the value is the rest of the line
+					if word == "ENV" && len(tokens) > 1 && !strings.Contains(line[tokens[0][0]:tokens[0][1]], "=") {
+						key := line[tokens[0][0]:tokens[0][1]]
+						start, end := unquoteSpan(line, tokens[1][0], tokens[len(tokens)-1][1])
+						if isCredentialKey(key) && isLiteralValue(line[start:end]) {
+							found := newDetection(i, start, end, "Dockerfile credential")
+							found.location = "ENV " + key
+							result = append(result, found)
+						}
+						tokens = nil
+					}
+				}
+			}
+
+			if instruction != "" {
+				for _, token := range tokens {
+					pair := line[token[0]:token[1]]
+					separator := strings.IndexByte(pair, '=')
+					if separator <= 0 {
+						continue
+					}
+
+					key := pair[:separator]
+					start, end := unquoteSpan(line, token[0]+separator+1, token[1])
+					if isCredentialKey(key) && isLiteralValue(line[start:end]) {
+						found := newDetection(i, start, end, "Dockerfile credential")
+						found.location = instruction + " " + key
+						result = append(result, found)
+					}
+				}
+			}
+
+			if !continues {
+				instruction = ""
+			}
+		}
+
+		return result
+	},
+}
+
+// splitDockerfileTokens splits a line on whitespace outside of quotes, returning the byte range of each token
+func splitDockerfileTokens(line string) [][2]int {
+	var tokens [][2]int
+	start := -1
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == ' ' || ch == '\t':
+			if start >= 0 {
+				tokens = append(tokens, [2]int{start, i})
+				start = -1
+			}
+			continue
+		case ch == '"' || ch == '\'':
+			quote = ch
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, [2]int{start, len(line)})
+	}
+
+	return tokens
+}
+
+// unquoteSpan removes matching quotes from around a value
+func unquoteSpan(line string, start int, end int) (int, int) {
+	if end-start >= 2 && (line[start] == '"' || line[start] == '\'') && line[end-1] == line[start] {
+		return start + 1, end - 1
+	}
+
+	return start, end
+}
+
+func isComposeFile(filePath string, documents []*yaml.Node) bool {
+	name := strings.ToLower(path.Base(filePath))
+	if strings.HasPrefix(name, "docker-compose") || strings.HasPrefix(name, "compose.") {
+		return true
+	}
+
+	// Otherwise look for services with images or builds, as other files have services too
+	for _, document := range documents {
+		services := document.Get("services")
+		if services == nil {
+			continue
+		}
+
+		for _, service := range services.Values {
+			if service.Get("image") != nil || service.Get("build") != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var composeDetector = builtInDetector{
+	name:     "docker-compose",
+	severity: SeverityHigh,
+	remediation: "Move the credential out of the compose file into an env_file which isn't committed, or use compose " +
+		"secrets, and rotate it.",
+	detect: func(input *detectorInput) []detection {
+		documents := input.yaml()
+		if !isComposeFile(input.path, documents) {
+			return nil
+		}
+
+		var result []detection
+		for _, document := range documents {
+			services := document.Get("services")
+			if services == nil {
+				continue
+			}
+
+			for i, serviceName := range services.Keys {
+				environment := services.Values[i].Get("environment")
+				if environment == nil {
+					continue
+				}
+
+				location := fmt.Sprintf("services.%s.environment.", serviceName)
+				for j, key := range environment.Keys {
+					value := environment.Values[j]
+					if value.Kind == yaml.ScalarNode && isCredentialKey(key) && isLiteralValue(value.Value) {
+						found := newDetection(value.Line-1, value.Start, value.End, "Compose environment credential")
+						found.location = location + key
+						result = append(result, found)
+					}
+				}
+
+				// Environment variables can also be a list of KEY=value
+				for _, item := range environment.Items {
+					separator := strings.IndexByte(item.Value, '=')
+					if item.Kind != yaml.ScalarNode || separator <= 0 || item.End-item.Start != len(item.Value) {
+						continue
+					}
+
+					key := item.Value[:separator]
+					if isCredentialKey(key) && isLiteralValue(item.Value[separator+1:]) {
+						found := newDetection(item.Line-1, item.Start+separator+1, item.End, "Compose environment credential")
+						found.location = location + key
+						result = append(result, found)
+					}
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+var kubernetesSecretDetector = builtInDetector{
+	name:     "kubernetes-secret",
+	severity: SeverityHigh,
+	remediation: "Remove the Secret manifest from the repository, as base64 encoding is not encryption, and rotate the " +
+		"values. Use Sealed Secrets, the External Secrets Operator or your cloud's secret store instead.",
+	detect: func(input *detectorInput) []detection {
+		var result []detection
+		for _, document := range input.yaml() {
+			if document.GetValue("kind") != "Secret" || document.GetValue("apiVersion") == "" {
+				continue
+			}
+
+			resource := "Secret/" + document.Get("metadata").GetValue("name")
+			for _, section := range []string{"data", "stringData"} {
+				values := document.Get(section)
+				if values == nil {
+					continue
+				}
+
+				for i, key := range values.Keys {
+					value := values.Values[i]
+					if value.Kind != yaml.ScalarNode || value.Start == value.End || !isLiteralValue(value.Value) {
+						continue
+					}
+
+					found := newDetection(value.Line-1, value.Start, value.End, "Kubernetes Secret value")
+					found.location = fmt.Sprintf("%s %s.%s", resource, section, key)
+
+					// Values in data are base64 encoded, and are checked after decoding them
+					if section == "data" {
+						decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value.Value))
+						if err != nil || len(strings.TrimSpace(string(decoded))) == 0 {
+							continue
+						}
+						found.decodedValue = string(decoded)
+					}
+
+					result = append(result, found)
+				}
+			}
+		}
+
+		return result
+	},
+}
+
+func isHelmValuesFile(filePath string) bool {
+	name := strings.ToLower(path.Base(filePath))
+	return strings.HasPrefix(name, "values") && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"))
+}
+
+var helmValuesDetector = builtInDetector{
+	name:     "helm-values",
+	severity: SeverityMedium,
+	remediation: "Remove the credential from the values file and pass it in at install time with --set or from an " +
+		"existing Secret, and rotate it.",
+	detect: func(input *detectorInput) []detection {
+		if !isHelmValuesFile(input.path) {
+			return nil
+		}
+
+		var result []detection
+		var walk func(node *yaml.Node, location string)
+		walk = func(node *yaml.Node, location string) {
+			for i, key := range node.Keys {
+				value := node.Values[i]
+				childLocation := key
+				if location != "" {
+					childLocation = location + "." + key
+				}
+
+				if value.Kind == yaml.ScalarNode {
+					if value.Start != value.End && isCredentialKey(key) && isLiteralValue(value.Value) {
+						found := newDetection(value.Line-1, value.Start, value.End, "Helm value")
+						found.location = childLocation
+						result = append(result, found)
+					}
+				} else {
+					walk(value, childLocation)
+				}
+			}
+
+			for i, item := range node.Items {
+				walk(item, fmt.Sprintf("%s[%d]", location, i))
+			}
+		}
+
+		for _, document := range input.yaml() {
+			walk(document, "")
+		}
+
+		return result
+	},
+}
@@ -0,0 +1,100 @@
+package scanning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManifestDetectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		content   string
+		locations []string
+		values    []string
+	}{
+		{
+			name: "dockerfile",
+			path: "build/Dockerfile",
+			content: "FROM alpine\n" +
+				"ARG NPM_TOKEN=npm_4f9a2b7c\n" +
+				"ENV DB_USER=orca DB_PASSWORD=\"s3cr3t pass\" \\\n" +
+				"    API_KEY=k-1234 API_KEY_FILE=/run/secrets/key\n" +
+				"ENV ADMIN_PASSWORD hunter2\n" +
+				"ENV TOKEN=${TOKEN}\n",
+			locations: []string{"ARG NPM_TOKEN", "ENV DB_PASSWORD", "ENV API_KEY", "ENV ADMIN_PASSWORD"},
+			values:    []string{"npm_4f9a2b7c", "s3cr3t pass", "k-1234", "hunter2"},
+		},
+		{
+			name: "compose",
+			path: "docker-compose.yml",
+			content: "services:\n" +
+				"  db:\n" +
+				"    image: postgres\n" +
+				"    environment:\n" +
+				"      POSTGRES_USER: orca\n" +
+				"      POSTGRES_PASSWORD: \"hunter2\"\n" +
+				"  app:\n" +
+				"    build: .\n" +
+				"    environment:\n" +
+				"      - JWT_SECRET=c0rrect-h0rse\n" +
+				"      - JWT_SECRET_FILE=/run/secrets/jwt\n",
+			locations: []string{"services.db.environment.POSTGRES_PASSWORD", "services.app.environment.JWT_SECRET"},
+			values:    []string{"hunter2", "c0rrect-h0rse"},
+		},
+		{
+			name: "kubernetes secret",
+			path: "deploy/secret.yaml",
+			content: "apiVersion: v1\n" +
+				"kind: Secret\n" +
+				"metadata:\n" +
+				"  name: db-creds\n" +
+				"data:\n" +
+				"  password: aHVudGVyMg==\n" +
+				"  placeholder: Y2hhbmdlbWU=\n" +
+				"stringData:\n" +
+				"  username: orca\n",
+			locations: []string{"Secret/db-creds data.password", "Secret/db-creds stringData.username"},
+			values:    []string{"aHVudGVyMg==", "orca"},
+		},
+		{
+			name: "helm values",
+			path: "charts/orca/values.yaml",
+			content: "postgresql:\n" +
+				"  auth:\n" +
+				"    username: orca\n" +
+				"    password: hunter2\n" +
+				"    existingSecret: \"\"\n" +
+				"ingress:\n" +
+				"  enabled: true\n",
+			locations: []string{"postgresql.auth.password"},
+			values:    []string{"hunter2"},
+		},
+		{
+			name:    "not a manifest",
+			path:    "README.md",
+			content: "ENV DB_PASSWORD=hunter2\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := &Scanner{}
+			result, err := scanner.ScanFileContent(test.path, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var locations []string
+			var values []string
+			for _, match := range result.Matches {
+				locations = append(locations, match.Location)
+				values = append(values, match.value)
+			}
+
+			if !reflect.DeepEqual(locations, test.locations) || !reflect.DeepEqual(values, test.values) {
+				t.Errorf("expected %v %v but got %v %v", test.locations, test.values, locations, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package scanning
+
+import (
+	"math"
+	"regexp/syntax"
+	"sort"
+)
+
+// maxSpecificity is the specificity of matches which are more specific than any pattern
+const maxSpecificity = math.MaxInt32
+
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+func (severity Severity) rank() int {
+	if rank, ok := severityRanks[severity]; ok {
+		return rank
+	}
+
+	return severityRanks[SeverityMedium]
+}
+
+// resolveOverlappingMatches reduces matches on a line whose spans overlap into a single match. The match from the
+// highest severity pattern is kept, or from the most specific pattern if the severities are the same, and the kinds of
+// the other matches are kept as secondary kinds. The merged match spans all of the matches, so that redacting it
+// redacts every part of the line that any of them found.
+func resolveOverlappingMatches(line string, matches []Match) []Match {
+	if len(matches) < 2 {
+		return matches
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].StartIndex < matches[j].StartIndex
+	})
+
+	var result []Match
+	group := []Match{matches[0]}
+	groupEnd := matches[0].EndIndex
+	for _, match := range matches[1:] {
+		if match.StartIndex < groupEnd {
+			group = append(group, match)
+			if match.EndIndex > groupEnd {
+				groupEnd = match.EndIndex
+			}
+			continue
+		}
+
+		result = append(result, mergeMatches(line, group))
+		group = []Match{match}
+		groupEnd = match.EndIndex
+	}
+
+	return append(result, mergeMatches(line, group))
+}
+
+func mergeMatches(line string, group []Match) Match {
+	primaryIndex := 0
+	for i, match := range group {
+		if outranks(match, group[primaryIndex]) {
+			primaryIndex = i
+		}
+	}
+
+	primary := group[primaryIndex]
+	widened := false
+	for i, match := range group {
+		if i == primaryIndex {
+			continue
+		}
+
+		if match.StartIndex < primary.StartIndex {
+			primary.StartIndex = match.StartIndex
+			primary.StartColumn = match.StartColumn
+			widened = true
+		}
+		if match.EndIndex > primary.EndIndex {
+			primary.EndIndex = match.EndIndex
+			primary.EndColumn = match.EndColumn
+			widened = true
+		}
+
+		for _, kind := range append([]string{match.Kind}, match.SecondaryKinds...) {
+			if kind != primary.Kind && !containsString(primary.SecondaryKinds, kind) {
+				primary.SecondaryKinds = append(primary.SecondaryKinds, kind)
+			}
+		}
+	}
+
+	// The value and its context no longer line up once the span has been widened by another match
+	if widened {
+		primary.value = line[primary.StartIndex:primary.EndIndex]
+		primary.context = ""
+		primary.contextOffset = 0
+	}
+
+	return primary
+}
+
+func outranks(match Match, other Match) bool {
+	if match.Severity.rank() != other.Severity.rank() {
+		return match.Severity.rank() > other.Severity.rank()
+	}
+
+	return match.specificity > other.specificity
+}
+
+// patternSpecificity estimates how specific a pattern is by counting the literal characters every match of it must
+// contain, so Bearer [a-zA-Z0-9]+ is more specific than [a-zA-Z0-9]+
+func patternSpecificity(expression string) int {
+	parsed, err := syntax.Parse(expression, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+
+	return literalWeight(parsed)
+}
+
+func literalWeight(expression *syntax.Regexp) int {
+	switch expression.Op {
+	case syntax.OpLiteral:
+		return len(expression.Rune)
+	case syntax.OpCapture:
+		return literalWeight(expression.Sub[0])
+	case syntax.OpPlus:
+		return literalWeight(expression.Sub[0])
+	case syntax.OpRepeat:
+		return literalWeight(expression.Sub[0]) * expression.Min
+	case syntax.OpConcat:
+		weight := 0
+		for _, sub := range expression.Sub {
+			weight += literalWeight(sub)
+		}
+		return weight
+	case syntax.OpAlternate:
+		weight := -1
+		for _, sub := range expression.Sub {
+			if subWeight := literalWeight(sub); weight < 0 || subWeight < weight {
+				weight = subWeight
+			}
+		}
+		if weight < 0 {
+			return 0
+		}
+		return weight
+	default:
+		return 0
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+
+	return false
+}
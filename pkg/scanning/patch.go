@@ -0,0 +1,129 @@
+package scanning
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type contentLine struct {
+	number int
+	text   string
+}
+
+// filePatch holds the lines added and removed by a unified diff, with added lines numbered as they appear in the new
+// version of the file
+type filePatch struct {
+	added   []contentLine
+	removed []string
+}
+
+// parsePatch parses the unified diff GitHub returns for a file in a commit. If the patch is truncated, so that a hunk
+// holds fewer lines than its header describes, then ok is false and the whole file needs to be scanned instead.
+func parsePatch(patch string) (result *filePatch, ok bool) {
+	result = &filePatch{}
+
+	// The remaining number of old and new lines expected in the current hunk
+	oldRemaining := 0
+	newRemaining := 0
+	newLineNumber := 0
+	inHunk := false
+
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if oldRemaining > 0 || newRemaining > 0 {
+				return nil, false
+			}
+
+			header := hunkHeaderRegex.FindStringSubmatch(line)
+			if header == nil {
+				return nil, false
+			}
+
+			oldRemaining = parseHunkCount(header[2])
+			newLineNumber, _ = strconv.Atoi(header[3])
+			newRemaining = parseHunkCount(header[4])
+			inHunk = true
+			continue
+		}
+
+		if !inHunk || strings.HasPrefix(line, "\\") {
+
+			// Skip anything before the first hunk and "\ No newline at end of file" markers
+			continue
+		}
+
+		if oldRemaining == 0 && newRemaining == 0 {
+
+			// A trailing new line at the end of the patch
+			if line == "" {
+				continue
+			}
+
+			return nil, false
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+"):
+			result.added = append(result.added, contentLine{number: newLineNumber, text: line[1:]})
+			newLineNumber++
+			newRemaining--
+		case strings.HasPrefix(line, "-"):
+			result.removed = append(result.removed, line[1:])
+			oldRemaining--
+		default:
+			newLineNumber++
+			oldRemaining--
+			newRemaining--
+		}
+
+		if oldRemaining < 0 || newRemaining < 0 {
+			return nil, false
+		}
+	}
+
+	if oldRemaining > 0 || newRemaining > 0 {
+		return nil, false
+	}
+
+	return result, true
+}
+
+// parseHunkCount parses the optional line count of a hunk header, which defaults to 1 when omitted
+func parseHunkCount(count string) int {
+	if count == "" {
+		return 1
+	}
+
+	value, _ := strconv.Atoi(count)
+	return value
+}
+
+// addedContent joins the lines added by the patch
+func (patch *filePatch) addedContent() string {
+	var lines []string
+	for _, line := range patch.added {
+		lines = append(lines, line.text)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// removedValue checks if a value was removed by the patch without being added back
+func (patch *filePatch) removedValue(value string) bool {
+	for _, line := range patch.added {
+		if strings.Contains(line.text, value) {
+			return false
+		}
+	}
+
+	for _, line := range patch.removed {
+		if strings.Contains(line, value) {
+			return true
+		}
+	}
+
+	return false
+}
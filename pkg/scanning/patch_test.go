@@ -0,0 +1,188 @@
+package scanning
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		ok      bool
+		added   []contentLine
+		removed []string
+	}{
+		{
+			name: "single hunk",
+			patch: strings.Join([]string{
+				"@@ -1,3 +1,3 @@",
+				" first",
+				"-token = old",
+				"+token = new",
+				" last",
+			}, "\n"),
+			ok:      true,
+			added:   []contentLine{{number: 2, text: "token = new"}},
+			removed: []string{"token = old"},
+		},
+		{
+			name: "multiple hunks",
+			patch: strings.Join([]string{
+				"@@ -10,2 +10,3 @@ func main() {",
+				" a",
+				"+b",
+				" c",
+				"@@ -40,3 +41,2 @@",
+				" x",
+				"-y",
+				" z",
+			}, "\n"),
+			ok:      true,
+			added:   []contentLine{{number: 11, text: "b"}},
+			removed: []string{"y"},
+		},
+		{
+			name: "lines numbered from the new start",
+			patch: strings.Join([]string{
+				"@@ -5,4 +7,5 @@",
+				" one",
+				"-two",
+				"+TWO",
+				"+2.5",
+				" three",
+				" four",
+			}, "\n"),
+			ok:      true,
+			added:   []contentLine{{number: 8, text: "TWO"}, {number: 9, text: "2.5"}},
+			removed: []string{"two"},
+		},
+		{
+			name: "counts omitted from the header",
+			patch: strings.Join([]string{
+				"@@ -3 +3 @@",
+				"-old",
+				"+new",
+			}, "\n"),
+			ok:      true,
+			added:   []contentLine{{number: 3, text: "new"}},
+			removed: []string{"old"},
+		},
+		{
+			name: "no new line at end of file",
+			patch: strings.Join([]string{
+				"@@ -1,2 +1,2 @@",
+				" first",
+				"-last",
+				"\\ No newline at end of file",
+				"+last = secret",
+				"\\ No newline at end of file",
+			}, "\n"),
+			ok:      true,
+			added:   []contentLine{{number: 2, text: "last = secret"}},
+			removed: []string{"last"},
+		},
+		{
+			name: "added line beginning with +++",
+			patch: strings.Join([]string{
+				"@@ -0,0 +1,2 @@",
+				"++++ b/file.txt",
+				"+--- a/file.txt",
+			}, "\n"),
+			ok:    true,
+			added: []contentLine{{number: 1, text: "+++ b/file.txt"}, {number: 2, text: "--- a/file.txt"}},
+		},
+		{
+			name: "trailing new line",
+			patch: "@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			ok:      true,
+			added:   []contentLine{{number: 1, text: "b"}},
+			removed: []string{"a"},
+		},
+		{
+			name: "truncated hunk",
+			patch: strings.Join([]string{
+				"@@ -1,3 +1,4 @@",
+				" first",
+				"+second",
+			}, "\n"),
+		},
+		{
+			name: "truncated before the next hunk",
+			patch: strings.Join([]string{
+				"@@ -1,2 +1,2 @@",
+				" first",
+				"@@ -9,1 +9,1 @@",
+				"-old",
+				"+new",
+			}, "\n"),
+		},
+		{
+			name: "more lines than the header describes",
+			patch: strings.Join([]string{
+				"@@ -1 +1 @@",
+				"-a",
+				"+b",
+				"+c",
+			}, "\n"),
+		},
+		{
+			name:  "invalid hunk header",
+			patch: "@@ -a,b +c,d @@\n+value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, ok := parsePatch(test.patch)
+			if ok != test.ok {
+				t.Fatalf("expected ok to be %t but got %t", test.ok, ok)
+			}
+			if !ok {
+				if patch != nil {
+					t.Errorf("expected no patch but got %+v", patch)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(patch.added, test.added) {
+				t.Errorf("expected %+v to be added but got %+v", test.added, patch.added)
+			}
+			if !reflect.DeepEqual(patch.removed, test.removed) {
+				t.Errorf("expected %q to be removed but got %q", test.removed, patch.removed)
+			}
+		})
+	}
+}
+
+func TestRemovedValue(t *testing.T) {
+	patch, ok := parsePatch(strings.Join([]string{
+		"@@ -1,3 +1,2 @@",
+		"-password = hunter2",
+		"-token = tok_123456",
+		"+token = tok_123456 # moved",
+		" unchanged = tok_999999",
+	}, "\n"))
+	if !ok {
+		t.Fatal("expected the patch to parse")
+	}
+
+	tests := []struct {
+		value   string
+		removed bool
+	}{
+		{"hunter2", true},
+		{"tok_123456", false},
+		{"tok_999999", false},
+		{"never-there", false},
+	}
+
+	for _, test := range tests {
+		if removed := patch.removedValue(test.value); removed != test.removed {
+			t.Errorf("expected %s to be removed: %t", test.value, test.removed)
+		}
+	}
+}
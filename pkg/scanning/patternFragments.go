@@ -0,0 +1,151 @@
+package scanning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PatternFile is an Orca patterns file. It is either a list of patterns, or an object which also has fragments: named
+// sub-expressions that patterns and exclusions can reference as {{name}} rather than repeating them.
+type PatternFile struct {
+	Fragments map[string]string `json:"fragments,omitempty"`
+	Patterns  []SearchPattern   `json:"patterns"`
+}
+
+// fragmentReferenceRegex matches a reference to a fragment, e.g. {{ipv4Octet}}
+var fragmentReferenceRegex = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_-]*)\s*\}\}`)
+
+// ParsePatternFile parses either form of patterns file, and checks that its fragments can be expanded. Patterns are
+// left as they were written, so that their fragments can be expanded with ExpandPattern.
+func ParsePatternFile(data []byte) (*PatternFile, error) {
+	var file PatternFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &file.Patterns); err != nil {
+		return nil, err
+	}
+
+	for _, name := range file.FragmentNames() {
+		if !fragmentReferenceRegex.MatchString("{{" + name + "}}") {
+			return nil, fmt.Errorf("invalid fragment name \"%s\"", name)
+		}
+
+		expression, err := file.expand(file.Fragments[name], []string{name})
+		if err != nil {
+			return nil, err
+		}
+
+		// Fragments are embedded in other expressions, so they can't have their own flags
+		if _, _, isLiteral := splitRegexLiteral(expression); isLiteral {
+			return nil, fmt.Errorf("invalid fragment \"%s\": fragments can't be regex literals", name)
+		}
+		if _, err := compilePattern(expression); err != nil {
+			return nil, fmt.Errorf("invalid fragment \"%s\": %v", name, err)
+		}
+	}
+
+	return &file, nil
+}
+
+// FragmentNames lists the file's fragments in order
+func (file *PatternFile) FragmentNames() []string {
+	var names []string
+	for name := range file.Fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ExpandPattern replaces the fragment references in a pattern and its exclusions with the fragments' expressions
+func (file *PatternFile) ExpandPattern(pattern SearchPattern) (SearchPattern, error) {
+	expression, err := file.expand(pattern.Pattern, nil)
+	if err != nil {
+		return pattern, fmt.Errorf("invalid pattern \"%s\": %v", pattern.Kind, err)
+	}
+	pattern.Pattern = expression
+
+	if len(pattern.Exclusions) > 0 {
+		exclusions := make([]string, len(pattern.Exclusions))
+		for i, exclusion := range pattern.Exclusions {
+			if exclusions[i], err = file.expand(exclusion, nil); err != nil {
+				return pattern, fmt.Errorf("invalid exclusion \"%s\" in pattern \"%s\": %v", exclusion, pattern.Kind, err)
+			}
+		}
+		pattern.Exclusions = exclusions
+	}
+
+	return pattern, nil
+}
+
+// UnusedFragments lists the fragments which no pattern, exclusion or other fragment references
+func (file *PatternFile) UnusedFragments() []string {
+	used := map[string]bool{}
+	markUsed := func(expression string) {
+		for _, reference := range fragmentReferenceRegex.FindAllStringSubmatch(expression, -1) {
+			used[reference[1]] = true
+		}
+	}
+
+	for _, pattern := range file.Patterns {
+		markUsed(pattern.Pattern)
+		for _, exclusion := range pattern.Exclusions {
+			markUsed(exclusion)
+		}
+	}
+	for _, fragment := range file.Fragments {
+		markUsed(fragment)
+	}
+
+	var unused []string
+	for _, name := range file.FragmentNames() {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+
+	return unused
+}
+
+// expand replaces fragment references in an expression. Each fragment is wrapped in a non-capturing group, so that it
+// can be quantified like a single token and doesn't change the numbering of the expression's own groups. The fragments
+// being expanded are tracked so that a fragment can't reference itself.
+func (file *PatternFile) expand(expression string, expanding []string) (string, error) {
+	var expansionErr error
+	expanded := fragmentReferenceRegex.ReplaceAllStringFunc(expression, func(reference string) string {
+		name := fragmentReferenceRegex.FindStringSubmatch(reference)[1]
+		fragment, ok := file.Fragments[name]
+		if !ok {
+			if expansionErr == nil {
+				expansionErr = fmt.Errorf("unknown fragment \"%s\"", name)
+			}
+			return reference
+		}
+
+		for _, outer := range expanding {
+			if outer == name {
+				if expansionErr == nil {
+					expansionErr = fmt.Errorf("fragment \"%s\" references itself: %s",
+						name, strings.Join(append(expanding, name), " -> "))
+				}
+				return reference
+			}
+		}
+
+		inner, err := file.expand(fragment, append(expanding[:len(expanding):len(expanding)], name))
+		if err != nil && expansionErr == nil {
+			expansionErr = err
+		}
+
+		return "(?:" + inner + ")"
+	})
+
+	return expanded, expansionErr
+}
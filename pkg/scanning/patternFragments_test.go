@@ -0,0 +1,55 @@
+package scanning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternFragments(t *testing.T) {
+	patterns, err := parsePatterns([]byte(`{
+		"fragments": {
+			"octet": "25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?",
+			"ipv4": "{{octet}}(\\.{{ octet }}){3}"
+		},
+		"patterns": [
+			{"pattern": "/host=({{ipv4}})/i", "kind": "Host", "exclusions": ["^host=127\\.{{octet}}"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	octet := `(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`
+	expected := []SearchPattern{{
+		Pattern:    `/host=((?:` + octet + `(\.` + octet + `){3}))/i`,
+		Kind:       "Host",
+		Exclusions: []string{`^host=127\.` + octet},
+	}}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("expected %+v but got %+v", expected, patterns)
+	}
+
+	if _, err := parsePatterns([]byte(`[{"pattern": "token", "kind": "Token"}]`)); err != nil {
+		t.Errorf("expected a list of patterns to still be supported but got %v", err)
+	}
+
+	for name, invalid := range map[string]string{
+		"unknown fragment":      `{"patterns": [{"pattern": "{{missing}}", "kind": "Test"}]}`,
+		"recursive fragments":   `{"fragments": {"a": "x{{b}}", "b": "{{a}}"}, "patterns": []}`,
+		"invalid fragment":      `{"fragments": {"a": "(x"}, "patterns": []}`,
+		"literal fragment":      `{"fragments": {"a": "/x/i"}, "patterns": []}`,
+		"invalid fragment name": `{"fragments": {"a b": "x"}, "patterns": []}`,
+	} {
+		if _, err := parsePatterns([]byte(invalid)); err == nil {
+			t.Errorf("expected an error for the %s", name)
+		}
+	}
+
+	file, err := ParsePatternFile([]byte(`{"fragments": {"a": "x", "b": "{{a}}", "c": "y"}, "patterns": [{"pattern": "{{b}}"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unused := file.UnusedFragments(); !reflect.DeepEqual(unused, []string{"c"}) {
+		t.Errorf("expected c to be unused but got %v", unused)
+	}
+}
@@ -0,0 +1,174 @@
+package scanning
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+type PatternFormat string
+
+const (
+	PatternFormatOrca          PatternFormat = "orca"
+	PatternFormatGitleaks      PatternFormat = "gitleaks"
+	PatternFormatGitSecrets    PatternFormat = "git-secrets"
+	PatternFormatDetectSecrets PatternFormat = "detect-secrets"
+)
+
+var patternFormats = []PatternFormat{
+	PatternFormatOrca,
+	PatternFormatGitleaks,
+	PatternFormatGitSecrets,
+	PatternFormatDetectSecrets,
+}
+
+// PatternImport is the result of converting another tool's rules into search patterns
+type PatternImport struct {
+	Patterns []SearchPattern
+	Skipped  []SkippedRule
+	Warnings []string
+}
+
+// SkippedRule is a rule which could not be converted into a search pattern
+type SkippedRule struct {
+	Rule   string
+	Reason string
+}
+
+func (result *PatternImport) skip(rule string, reason string) {
+	result.Skipped = append(result.Skipped, SkippedRule{Rule: rule, Reason: reason})
+}
+
+func (result *PatternImport) warn(format string, args ...interface{}) {
+	result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// add validates a converted pattern before adding it, skipping it if it can't be used
+func (result *PatternImport) add(rule string, pattern SearchPattern) {
+	if err := pattern.Validate(); err != nil {
+		result.skip(rule, err.Error())
+		return
+	}
+
+	result.Patterns = append(result.Patterns, pattern)
+}
+
+// ParsePatternFormat parses the name of a rule format
+func ParsePatternFormat(name string) (PatternFormat, error) {
+	for _, format := range patternFormats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported pattern format \"%s\"", name)
+}
+
+// DetectPatternFormat guesses the format of a rules file from its name. git-secrets pattern lists have no
+// distinguishing name, so they always need to be named explicitly.
+func DetectPatternFormat(path string) PatternFormat {
+	name := filepath.Base(path)
+	switch {
+	case strings.EqualFold(filepath.Ext(name), ".toml"):
+		return PatternFormatGitleaks
+	case strings.EqualFold(filepath.Ext(name), ".baseline"):
+		return PatternFormatDetectSecrets
+	default:
+		return PatternFormatOrca
+	}
+}
+
+// splitPatternLocation splits an optional "format:" prefix from a patterns location, e.g. git-secrets:patterns.txt.
+// Without a prefix, the format is detected from the location's name.
+func splitPatternLocation(patternsLocation string) (PatternFormat, string) {
+	for _, format := range patternFormats {
+		prefix := string(format) + ":"
+		if strings.HasPrefix(patternsLocation, prefix) {
+			return format, strings.TrimPrefix(patternsLocation, prefix)
+		}
+	}
+
+	return DetectPatternFormat(patternsLocation), patternsLocation
+}
+
+// ImportPatterns converts rules in another tool's format into search patterns
+func ImportPatterns(format PatternFormat, data []byte) (*PatternImport, error) {
+	switch format {
+	case PatternFormatGitleaks:
+		return ImportGitleaksRules(data)
+	case PatternFormatGitSecrets:
+		return ImportGitSecretsPatterns(data)
+	case PatternFormatDetectSecrets:
+		return ImportDetectSecretsPlugins(data)
+	case PatternFormatOrca:
+		patterns, err := parsePatterns(data)
+		if err != nil {
+			return nil, err
+		}
+		return &PatternImport{Patterns: patterns}, nil
+	default:
+		return nil, errors.New("unsupported pattern format")
+	}
+}
+
+func logPatternImport(location string, result *PatternImport) {
+	for _, skipped := range result.Skipped {
+		log.Printf("Skipped rule \"%s\" from %s: %s\n", skipped.Rule, location, skipped.Reason)
+	}
+
+	for _, warning := range result.Warnings {
+		log.Printf("Warning importing %s: %s\n", location, warning)
+	}
+}
+
+// nameSecretGroup names the numbered capture group of a pattern as the secret group
+func nameSecretGroup(expression string, group int) (string, error) {
+	groupNumber := 0
+	inClass := false
+	for i := 0; i < len(expression); i++ {
+		switch ch := expression[i]; {
+		case ch == '\\':
+			i++
+		case inClass:
+			if ch == ']' {
+				inClass = false
+			}
+		case ch == '[':
+			inClass = true
+			if strings.HasPrefix(expression[i:], "[]") {
+				i++
+			} else if strings.HasPrefix(expression[i:], "[^]") {
+				i += 2
+			}
+		case ch == '(':
+			rest := expression[i+1:]
+			nameStart := 0
+			if strings.HasPrefix(rest, "?P<") {
+				nameStart = 3
+			} else if strings.HasPrefix(rest, "?<") && !strings.HasPrefix(rest, "?<=") && !strings.HasPrefix(rest, "?<!") {
+				nameStart = 2
+			} else if strings.HasPrefix(rest, "?") {
+				continue
+			}
+
+			groupNumber++
+			if groupNumber != group {
+				continue
+			}
+
+			if nameStart == 0 {
+				return expression[:i+1] + "?P<" + SecretGroupName + ">" + rest, nil
+			}
+
+			nameEnd := strings.Index(rest, ">")
+			if nameEnd < 0 {
+				return "", errors.New("unterminated group name")
+			}
+			return expression[:i+1] + "?P<" + SecretGroupName + rest[nameEnd:], nil
+		}
+	}
+
+	return "", fmt.Errorf("capture group %d does not exist", group)
+}
@@ -1,12 +1,15 @@
 package scanning
 
 import (
-	"encoding/json"
+	"Orca/pkg/crypto"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"strings"
+	"sync"
 )
 
 type PatternStore interface {
@@ -31,19 +34,143 @@ func (store *FilePatternStore) GetPatterns() ([]SearchPattern, error) {
 		return nil, err
 	}
 
-	var result []SearchPattern
-	if err := json.Unmarshal(byteValue, &result); err != nil {
+	return parsePatterns(byteValue)
+}
+
+// ImportedPatternStore loads patterns from another tool's rules file, converting them each time they are loaded
+type ImportedPatternStore struct {
+	Format    PatternFormat
+	RulesFile string
+}
+
+func (store *ImportedPatternStore) GetPatterns() ([]SearchPattern, error) {
+	data, err := ioutil.ReadFile(store.RulesFile)
+	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	result, err := ImportPatterns(store.Format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	logPatternImport(store.RulesFile, result)
+
+	return result.Patterns, nil
+}
+
+// SignedPatternStore only loads patterns whose detached Ed25519 signature verifies against one of the trusted keys.
+// Patterns are loaded again every time they are requested, so if the file is replaced by one that doesn't verify, the
+// last verified patterns are used instead.
+type SignedPatternStore struct {
+	Format        PatternFormat
+	PatternsFile  string
+	SignatureFile string
+	PublicKeys    []ed25519.PublicKey
+
+	mutex        sync.Mutex
+	lastVerified []SearchPattern
+}
+
+func (store *SignedPatternStore) GetPatterns() ([]SearchPattern, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	patterns, err := store.loadVerifiedPatterns()
+	if err != nil {
+		if store.lastVerified == nil {
+			return nil, err
+		}
+
+		log.Printf("Could not load patterns from %s, using the last verified patterns: %v\n", store.PatternsFile, err)
+		return store.lastVerified, nil
+	}
+
+	store.lastVerified = patterns
+	return patterns, nil
+}
+
+func (store *SignedPatternStore) loadVerifiedPatterns() ([]SearchPattern, error) {
+	data, err := ioutil.ReadFile(store.PatternsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := ioutil.ReadFile(store.SignatureFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := crypto.VerifyDetached(data, signature, store.PublicKeys); err != nil {
+		return nil, fmt.Errorf("patterns in %s failed signature verification: %v", store.PatternsFile, err)
+	}
+
+	result, err := ImportPatterns(store.Format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	logPatternImport(store.PatternsFile, result)
+
+	return result.Patterns, nil
 }
 
+// NewSignedPatternStore creates a store for signed patterns at a location. If no signature location is given, the
+// signature is expected alongside the patterns with a .sig extension.
+func NewSignedPatternStore(
+	patternsLocation string,
+	signatureLocation string,
+	publicKeys []ed25519.PublicKey) (PatternStore, error) {
+
+	if len(publicKeys) == 0 {
+		return nil, errors.New("at least one public key is needed to verify signed patterns")
+	}
+
+	format, patternsLocation := splitPatternLocation(patternsLocation)
+	if strings.HasPrefix(patternsLocation, "http") {
+		// Todo
+		return nil, errors.New("fetching patterns from a URL is not yet implemented")
+	} else if !fileExists(patternsLocation) {
+		errorMessage := fmt.Sprintf("unsupported patterns location \"%s\"\n", patternsLocation)
+		return nil, errors.New(errorMessage)
+	}
+
+	if len(signatureLocation) == 0 {
+		signatureLocation = SignatureLocation(patternsLocation)
+	}
+
+	store := &SignedPatternStore{
+		Format:        format,
+		PatternsFile:  patternsLocation,
+		SignatureFile: signatureLocation,
+		PublicKeys:    publicKeys,
+	}
+
+	// Refuse to start without a verified set of patterns to fall back on
+	if _, err := store.GetPatterns(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// SignatureLocation is where the detached signature of a patterns file is expected by default
+func SignatureLocation(patternsLocation string) string {
+	return patternsLocation + ".sig"
+}
+
+// NewPatternStore creates a store for the patterns at a location. The location can be prefixed with the format of the
+// rules it holds, e.g. gitleaks:rules.toml, otherwise the format is detected from its name.
 func NewPatternStore(patternsLocation string) (PatternStore, error) {
+	format, patternsLocation := splitPatternLocation(patternsLocation)
 	if strings.HasPrefix(patternsLocation, "http") {
 		// Todo
 		return nil, errors.New("fetching patterns from a URL is not yet implemented")
 	} else if fileExists(patternsLocation) {
+		if format != PatternFormatOrca {
+			return &ImportedPatternStore{Format: format, RulesFile: patternsLocation}, nil
+		}
+
 		store := &FilePatternStore{PatternsJsonFile: patternsLocation}
 		return store, nil
 	} else {
@@ -52,6 +179,37 @@ func NewPatternStore(patternsLocation string) (PatternStore, error) {
 	}
 }
 
+// parsePatterns parses an Orca patterns file, expanding the fragments in its patterns
+func parsePatterns(data []byte) ([]SearchPattern, error) {
+	file, err := ParsePatternFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]SearchPattern, len(file.Patterns))
+	for i, pattern := range file.Patterns {
+		if result[i], err = file.ExpandPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validatePatterns(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func validatePatterns(patterns []SearchPattern) error {
+	for _, pattern := range patterns {
+		if err := pattern.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // fileExists checks if a file exists and is not a directory before we
 // try using it to prevent further errors.
 func fileExists(filename string) bool {
@@ -0,0 +1,89 @@
+package scanning
+
+import (
+	"Orca/pkg/crypto"
+	"crypto/ed25519"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignedPatternStoreKeepsLastVerifiedPatterns(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory, err := ioutil.TempDir("", "orca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patternsFile := filepath.Join(directory, "patterns.json")
+	writeSignedPatterns(t, patternsFile, `[{"pattern": "(secret)", "kind": "Test Secret"}]`, privateKey)
+
+	store, err := NewSignedPatternStore(patternsFile, "", []ed25519.PublicKey{publicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tamper with the patterns without updating the signature
+	if err := ioutil.WriteFile(patternsFile, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := store.GetPatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patterns) != 1 || patterns[0].Kind != "Test Secret" {
+		t.Errorf("expected the last verified patterns but got %#v", patterns)
+	}
+
+	// Properly signed patterns are swapped in
+	writeSignedPatterns(t, patternsFile, `[{"pattern": "token", "kind": "Token"}]`, privateKey)
+	patterns, err = store.GetPatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patterns) != 1 || patterns[0].Kind != "Token" {
+		t.Errorf("expected the newly signed patterns but got %#v", patterns)
+	}
+}
+
+func TestSignedPatternStoreRefusesUnverifiedPatterns(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, otherPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory, err := ioutil.TempDir("", "orca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patternsFile := filepath.Join(directory, "patterns.json")
+	writeSignedPatterns(t, patternsFile, `[{"pattern": "(secret)", "kind": "Test Secret"}]`, otherPrivateKey)
+
+	if _, err := NewSignedPatternStore(patternsFile, "", []ed25519.PublicKey{publicKey}); err == nil {
+		t.Error("expected patterns signed by an untrusted key to be refused")
+	}
+}
+
+func writeSignedPatterns(t *testing.T, patternsFile string, patterns string, privateKey ed25519.PrivateKey) {
+	if err := ioutil.WriteFile(patternsFile, []byte(patterns), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signature := crypto.SignDetached([]byte(patterns), privateKey)
+	if err := ioutil.WriteFile(SignatureLocation(patternsFile), signature, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
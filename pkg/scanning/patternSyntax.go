@@ -0,0 +1,116 @@
+package scanning
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// translatePattern converts a pattern written as a JavaScript/PCRE style literal, e.g. /api_key\s*=/gi, into RE2
+// syntax, moving its flags into an inline flag group. Patterns that aren't written as literals are returned as they
+// are, including ones which only look like literals, such as /home/secret. Either way, the pattern is checked for
+// constructs RE2 can't support so that we can explain what is wrong.
+func translatePattern(pattern string) (string, error) {
+	expression := pattern
+	var inlineFlags string
+
+	if body, flags, isLiteral := splitRegexLiteral(pattern); isLiteral {
+		expression = body
+		for _, flag := range flags {
+			switch flag {
+			case 'i', 'm', 's', 'U':
+				if !strings.ContainsRune(inlineFlags, flag) {
+					inlineFlags += string(flag)
+				}
+			case 'g', 'u':
+				// Every match is always found and patterns are always UTF-8, so these change nothing
+			}
+		}
+	}
+
+	if err := checkUnsupportedSyntax(expression); err != nil {
+		return "", err
+	}
+
+	// RE2 only supports named groups in the (?P<name>) form
+	expression = javaScriptNamedGroupRegex.ReplaceAllString(expression, "${1}(?P<${2}")
+
+	if len(inlineFlags) > 0 {
+		expression = fmt.Sprintf("(?%s)%s", inlineFlags, expression)
+	}
+
+	return expression, nil
+}
+
+var (
+	regexLiteralFlagsRegex    = regexp.MustCompile(`^[imsuUg]*$`)
+	javaScriptNamedGroupRegex = regexp.MustCompile(`((?:^|[^\\])(?:\\\\)*)\(\?<([a-zA-Z_])`)
+)
+
+// splitRegexLiteral splits a /body/flags literal into its body and flags. Only flags which can be translated are
+// accepted, so anything else after the last slash means the pattern isn't a literal.
+func splitRegexLiteral(pattern string) (body string, flags string, isLiteral bool) {
+	if len(pattern) < 2 || pattern[0] != '/' {
+		return "", "", false
+	}
+
+	closingIndex := strings.LastIndex(pattern, "/")
+	if closingIndex < 1 || !regexLiteralFlagsRegex.MatchString(pattern[closingIndex+1:]) {
+		return "", "", false
+	}
+
+	return pattern[1:closingIndex], pattern[closingIndex+1:], true
+}
+
+// checkUnsupportedSyntax looks for PCRE constructs which RE2 can't support because they require backtracking
+func checkUnsupportedSyntax(expression string) error {
+	inClass := false
+	for i := 0; i < len(expression); i++ {
+		ch := expression[i]
+		rest := expression[i:]
+
+		if ch == '\\' && i+1 < len(expression) {
+			next := expression[i+1]
+			if !inClass && next >= '1' && next <= '9' {
+				return fmt.Errorf("backreference \"\\%c\" is not supported by RE2", next)
+			}
+			if !inClass && next == 'k' && i+2 < len(expression) && (expression[i+2] == '<' || expression[i+2] == '{') {
+				return fmt.Errorf("named backreferences are not supported by RE2")
+			}
+
+			i++
+			continue
+		}
+
+		if inClass {
+			if ch == ']' {
+				inClass = false
+			}
+			continue
+		}
+
+		switch {
+		case ch == '[':
+			inClass = true
+
+			// A closing bracket straight after the opening one (or its negation) is part of the class
+			if strings.HasPrefix(rest, "[]") {
+				i++
+			} else if strings.HasPrefix(rest, "[^]") {
+				i += 2
+			}
+		case strings.HasPrefix(rest, "(?="), strings.HasPrefix(rest, "(?!"):
+			return fmt.Errorf("lookahead \"%s\" is not supported by RE2", rest[:3])
+		case strings.HasPrefix(rest, "(?<="), strings.HasPrefix(rest, "(?<!"):
+			return fmt.Errorf("lookbehind \"%s\" is not supported by RE2", rest[:4])
+		case strings.HasPrefix(rest, "(?>"):
+			return fmt.Errorf("atomic groups are not supported by RE2")
+		case strings.HasPrefix(rest, "(?R"), strings.HasPrefix(rest, "(?&"), len(rest) > 2 && rest[:2] == "(?" && rest[2] >= '0' && rest[2] <= '9':
+			return fmt.Errorf("recursive patterns are not supported by RE2")
+		case (ch == '*' || ch == '+' || ch == '?' || ch == '}') && strings.HasPrefix(rest[1:], "+"):
+			return fmt.Errorf("possessive quantifier \"%c+\" is not supported by RE2", ch)
+		}
+	}
+
+	return nil
+}
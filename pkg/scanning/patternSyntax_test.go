@@ -0,0 +1,48 @@
+package scanning
+
+import "testing"
+
+func TestTranslatePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		expected string
+		invalid  bool
+	}{
+		{"plain pattern", `secret\d+`, `secret\d+`, false},
+		{"literal with flags", `/api[_-]key/gi`, `(?i)api[_-]key`, false},
+		{"literal with all flags", `/a.b/gims`, `(?ims)a.b`, false},
+		{"literal without flags", `/a\/b/`, `a\/b`, false},
+		{"named group", `/key=(?<secret>\w+)/`, `key=(?P<secret>\w+)`, false},
+		{"ungreedy flag", `/a.+b/U`, `(?U)a.+b`, false},
+		{"unknown flag", `/abc/x`, `/abc/x`, false},
+		{"path", `/home/secret`, `/home/secret`, false},
+		{"lookahead", `password(?=\d)`, "", true},
+		{"negative lookahead", `/token(?!s)/i`, "", true},
+		{"lookbehind", `(?<=key=)\w+`, "", true},
+		{"backreference", `(["'])\w+\1`, "", true},
+		{"named backreference", `(?<q>["'])\w+\k<q>`, "", true},
+		{"possessive quantifier", `a++b`, "", true},
+		{"lookahead syntax in class", `[(?=]`, `[(?=]`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := translatePattern(test.pattern)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("expected an error but got %q", actual)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
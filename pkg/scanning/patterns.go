@@ -1,20 +1,92 @@
 package scanning
 
-import "regexp"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// SecretGroupName is the name of the capture group holding the secret within a pattern. If a pattern has this group,
+// only the group is reported and redacted, and the rest of the match is kept as context.
+const SecretGroupName = "secret"
 
 type SearchPattern struct {
-	Pattern    string
-	Kind       string
-	Exclusions []string
+	Pattern    string   `json:"pattern"`
+	Kind       string   `json:"kind"`
+	Exclusions []string `json:"exclusions,omitempty"`
+
+	// Keywords are checked before the pattern runs. If any are given, a line must contain at least one of them
+	// (ignoring case) for the pattern to be checked against it.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// Entropy is the minimum Shannon entropy, in bits per character, a matched value must have to be reported
+	Entropy float64 `json:"entropy,omitempty"`
+
+	// Severity is low, medium, high or critical, and defaults to medium
+	Severity Severity `json:"severity,omitempty"`
+
+	// Paths and ExcludePaths are globs, e.g. **/appsettings.*.json, and Extensions are file extensions such as json.
+	// They restrict which files the pattern is checked against.
+	Paths        []string `json:"paths,omitempty"`
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+	Extensions   []string `json:"extensions,omitempty"`
+
+	// MinLength and MaxLength limit the number of characters in a matched value, and CharacterClasses lists the
+	// character classes it must contain: lower, upper, digit or symbol
+	MinLength        int      `json:"minLength,omitempty"`
+	MaxLength        int      `json:"maxLength,omitempty"`
+	CharacterClasses []string `json:"characterClasses,omitempty"`
+
+	// Scope is where the pattern can match in source files whose language Orca can lex. It defaults to anywhere, and can
+	// be code to only match string literals and the right hand side of assignments, dropping matches in comments and
+	// identifiers. Code suits patterns which often match prose or names, such as a bare password.
+	Scope string `json:"scope,omitempty"`
 }
 
 func (pattern *SearchPattern) GetRegexp() (*regexp.Regexp, error) {
-	return regexp.Compile(pattern.Pattern)
+	return compilePattern(pattern.Pattern)
+}
+
+// Validate checks that the pattern and its exclusions can be compiled, so that bad patterns are caught when they are
+// loaded rather than when they are first used
+func (pattern *SearchPattern) Validate() error {
+	if _, err := pattern.GetRegexp(); err != nil {
+		return fmt.Errorf("invalid pattern \"%s\": %v", pattern.Kind, err)
+	}
+
+	if _, ok := severityRanks[pattern.GetSeverity()]; !ok {
+		return fmt.Errorf("invalid severity \"%s\" in pattern \"%s\"", pattern.Severity, pattern.Kind)
+	}
+
+	if pattern.Scope != "" && pattern.Scope != PatternScopeCode && pattern.Scope != PatternScopeAnywhere {
+		return fmt.Errorf("invalid scope \"%s\" in pattern \"%s\"", pattern.Scope, pattern.Kind)
+	}
+
+	for _, exclusion := range pattern.Exclusions {
+		if _, err := compilePattern(exclusion); err != nil {
+			return fmt.Errorf("invalid exclusion \"%s\" in pattern \"%s\": %v", exclusion, pattern.Kind, err)
+		}
+	}
+
+	return pattern.validateConstraints()
+}
+
+func (pattern *SearchPattern) GetSeverity() Severity {
+	if len(pattern.Severity) == 0 {
+		return SeverityMedium
+	}
+
+	return pattern.Severity
 }
 
 func (pattern *SearchPattern) CanIgnore(value string) bool {
 	for _, exclusionPatternString := range pattern.Exclusions {
-		exclusionPattern := regexp.MustCompile(exclusionPatternString)
+		exclusionPattern, err := compilePattern(exclusionPatternString)
+		if err != nil {
+			continue
+		}
+
 		if exclusionPattern.MatchString(value) {
 			return true
 		}
@@ -22,3 +94,45 @@ func (pattern *SearchPattern) CanIgnore(value string) bool {
 
 	return false
 }
+
+// HasKeyword checks if a line, which must already be lower case, contains any of the pattern's keywords
+func (pattern *SearchPattern) HasKeyword(lowerCaseLine string) bool {
+	if len(pattern.Keywords) == 0 {
+		return true
+	}
+
+	for _, keyword := range pattern.Keywords {
+		if strings.Contains(lowerCaseLine, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shannonEntropy calculates the Shannon entropy of a value in bits per character
+func shannonEntropy(value string) float64 {
+	counts := map[rune]int{}
+	total := 0
+	for _, ch := range value {
+		counts[ch]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		probability := float64(count) / float64(total)
+		entropy -= probability * math.Log2(probability)
+	}
+
+	return entropy
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	expression, err := translatePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return regexp.Compile(expression)
+}
@@ -2,6 +2,7 @@ package scanning
 
 import (
 	"Orca/pkg/caching"
+	"context"
 	"github.com/google/go-github/v33/github"
 	"sort"
 )
@@ -11,14 +12,40 @@ type Result interface {
 }
 
 type CommitScanResult struct {
-	Commit  string
-	Matches []FileContentMatch
+	Commit     string
+	Matches    []FileContentMatch
+	Incomplete []IncompleteScan
+	Skipped    []SkippedFile
 }
 
 func (result *CommitScanResult) HasMatches() bool {
 	return len(result.Matches) > 0
 }
 
+func (result *CommitScanResult) IsIncomplete() bool {
+	return len(result.Incomplete) > 0
+}
+
+func AnyCommitHasMatches(results []CommitScanResult) bool {
+	for _, result := range results {
+		if result.HasMatches() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func AnyCommitIsIncomplete(results []CommitScanResult) bool {
+	for _, result := range results {
+		if result.IsIncomplete() {
+			return true
+		}
+	}
+
+	return false
+}
+
 type IssueScanResult struct {
 	Matches []LineMatch
 }
@@ -51,7 +78,10 @@ func (result *PullRequestReviewCommentScanResult) HasMatches() bool {
 	return len(result.Matches) > 0
 }
 
-func (scanner *Scanner) CheckPush(push *github.PushEvent, githubClient *github.Client) ([]CommitScanResult, error) {
+func (scanner *Scanner) CheckPush(
+	ctx context.Context,
+	push *github.PushEvent,
+	githubClient *github.Client) ([]CommitScanResult, error) {
 
 	// Sort the commits by their date
 	sort.Slice(push.Commits, func(i, j int) bool {
@@ -65,7 +95,7 @@ func (scanner *Scanner) CheckPush(push *github.PushEvent, githubClient *github.C
 
 		// Added files
 		for _, file := range commit.Added {
-			fileQueries = append(fileQueries,caching. GitHubFileQuery{
+			fileQueries = append(fileQueries, caching.GitHubFileQuery{
 				RepoOwner: *push.Repo.Owner.Login,
 				RepoName:  *push.Repo.Name,
 				CommitSHA: *commit.ID,
@@ -97,13 +127,13 @@ func (scanner *Scanner) CheckPush(push *github.PushEvent, githubClient *github.C
 		}
 	}
 
-	return scanner.CheckFileContentFromQueries(githubClient, fileQueries)
+	return scanner.CheckFileContentFromQueries(ctx, githubClient, fileQueries)
 }
 
-func (scanner *Scanner) CheckIssue(issue *github.IssuesEvent) (*IssueScanResult, error) {
+func (scanner *Scanner) CheckIssue(ctx context.Context, issue *github.IssuesEvent) (*IssueScanResult, error) {
 
 	// Check the Issue body
-	matches, err := scanner.CheckContent(*issue.Issue.Body)
+	matches, err := scanner.CheckProse(ctx, *issue.Issue.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -115,10 +145,12 @@ func (scanner *Scanner) CheckIssue(issue *github.IssuesEvent) (*IssueScanResult,
 	return &result, nil
 }
 
-func (scanner *Scanner) CheckIssueComment(issueComment *github.IssueCommentEvent) (*IssueScanResult, error) {
+func (scanner *Scanner) CheckIssueComment(
+	ctx context.Context,
+	issueComment *github.IssueCommentEvent) (*IssueScanResult, error) {
 
 	// Check the Issue Comment body
-	matches, err := scanner.CheckContent(*issueComment.Comment.Body)
+	matches, err := scanner.CheckProse(ctx, *issueComment.Comment.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -130,12 +162,14 @@ func (scanner *Scanner) CheckIssueComment(issueComment *github.IssueCommentEvent
 	return &result, nil
 }
 
-func (scanner *Scanner) CheckPullRequest(pullRequest *github.PullRequestEvent) (*PullRequestScanResult, error) {
+func (scanner *Scanner) CheckPullRequest(
+	ctx context.Context,
+	pullRequest *github.PullRequestEvent) (*PullRequestScanResult, error) {
 
 	// NOTE: commits are checked via a CI check, see checkSuiteHandler.go
 
 	// Check the Pull Request body
-	matches, err := scanner.CheckContent(*pullRequest.PullRequest.Body)
+	matches, err := scanner.CheckProse(ctx, *pullRequest.PullRequest.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -148,10 +182,11 @@ func (scanner *Scanner) CheckPullRequest(pullRequest *github.PullRequestEvent) (
 }
 
 func (scanner *Scanner) CheckPullRequestReview(
+	ctx context.Context,
 	pullRequestReview *github.PullRequestReviewEvent) (*PullRequestReviewScanResult, error) {
 
 	// Check the Pull Request Review body
-	matches, err := scanner.CheckContent(*pullRequestReview.Review.Body)
+	matches, err := scanner.CheckProse(ctx, *pullRequestReview.Review.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -164,10 +199,11 @@ func (scanner *Scanner) CheckPullRequestReview(
 }
 
 func (scanner *Scanner) CheckPullRequestReviewComment(
+	ctx context.Context,
 	pullRequestReviewComment *github.PullRequestReviewCommentEvent) (*PullRequestReviewCommentScanResult, error) {
 
 	// Check the Pull Request Review Comment body
-	matches, err := scanner.CheckContent(*pullRequestReviewComment.Comment.Body)
+	matches, err := scanner.CheckProse(ctx, *pullRequestReviewComment.Comment.Body)
 	if err != nil {
 		return nil, err
 	}
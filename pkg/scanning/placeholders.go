@@ -0,0 +1,240 @@
+package scanning
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// PlaceholderOptions configures how values that are placeholders rather than real secrets are recognised, e.g.
+// ${DB_PASSWORD}, #{Octopus.Variable}, changeme or xxxxxxxx
+type PlaceholderOptions struct {
+	Disabled bool `json:"disabled,omitempty"`
+
+	// DisabledRules turns off built-in rules by name, see placeholderSyntaxes and the rule name constants
+	DisabledRules []string `json:"disabledRules,omitempty"`
+
+	// Words and Patterns add to the built-in placeholder words and syntaxes
+	Words    []string `json:"words,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+const (
+	placeholderRuleRepeatedCharacters = "repeated-characters"
+	placeholderRuleDictionary         = "dictionary"
+)
+
+type placeholderSyntax struct {
+	name  string
+	regex *regexp.Regexp
+
+	// wholeValue syntaxes are too easily confused with real values or markup to be found within a line, so they are
+	// only placeholders when they are the entire value
+	wholeValue bool
+}
+
+// placeholderSyntaxes are variable and template interpolation syntaxes. GitHub Actions expressions are listed before
+// shell variables so that ${{ secrets.TOKEN }} isn't partly matched as ${{ secrets.TOKEN }. A bare shell variable
+// must be an upper case environment variable name such as $DB_PASSWORD, as a password can start with a $, and angle
+// brackets must hold a single name, as XML tags hold real values in their attributes.
+var placeholderSyntaxes = []placeholderSyntax{
+	{"github-actions", regexp.MustCompile(`\$\{\{.*?\}\}`), false},
+	{"shell", regexp.MustCompile(`\$\{[^{}]+\}`), false},
+	{"shell", regexp.MustCompile(`^\$[A-Z_][A-Z0-9_]*$`), true},
+	{"octopus", regexp.MustCompile(`#\{[^{}]+\}`), false},
+	{"template", regexp.MustCompile(`\{\{.*?\}\}|\{%.*?%\}|<%=?.*?%>`), false},
+	{"azure-devops", regexp.MustCompile(`\$\([^()]+\)|\$\[[^\[\]]+\]`), false},
+	{"windows", regexp.MustCompile(`%[A-Za-z_][A-Za-z0-9_]*%`), false},
+	{"angle-brackets", regexp.MustCompile(`^<[A-Za-z_][A-Za-z0-9_.-]*>$`), true},
+}
+
+var placeholderWords = []string{
+	"changeme",
+	"changeit",
+	"replaceme",
+	"placeholder",
+	"example",
+	"sample",
+	"dummy",
+	"redacted",
+	"removed",
+	"todo",
+	"tbd",
+	"fixme",
+	"foobar",
+	"null",
+	"nil",
+	"none",
+	"undefined",
+	"empty",
+	"notset",
+	"insertkeyhere",
+	"yourkeyhere",
+	"yourtokenhere",
+	"yourpasswordhere",
+	"yoursecrethere",
+	"yourapikeyhere",
+}
+
+// placeholderClassifier recognises placeholder values
+type placeholderClassifier struct {
+	syntaxes         []placeholderSyntax
+	words            map[string]bool
+	patterns         []*regexp.Regexp
+	repeatCharacters bool
+}
+
+func newPlaceholderClassifier(options PlaceholderOptions) *placeholderClassifier {
+	if options.Disabled {
+		return nil
+	}
+
+	disabled := map[string]bool{}
+	for _, rule := range options.DisabledRules {
+		disabled[rule] = true
+	}
+
+	classifier := &placeholderClassifier{
+		words:            map[string]bool{},
+		repeatCharacters: !disabled[placeholderRuleRepeatedCharacters],
+	}
+
+	for _, syntax := range placeholderSyntaxes {
+		if !disabled[syntax.name] {
+			classifier.syntaxes = append(classifier.syntaxes, syntax)
+		}
+	}
+
+	if !disabled[placeholderRuleDictionary] {
+		for _, word := range placeholderWords {
+			classifier.words[word] = true
+		}
+	}
+
+	for _, word := range options.Words {
+		classifier.words[normalisePlaceholderWord(word)] = true
+	}
+
+	for _, pattern := range options.Patterns {
+		regex, err := compilePattern(pattern)
+		if err != nil {
+			continue
+		}
+		classifier.patterns = append(classifier.patterns, regex)
+	}
+
+	return classifier
+}
+
+// isPlaceholder checks if a matched value is a placeholder instead of a real secret
+func (classifier *placeholderClassifier) isPlaceholder(value string) bool {
+	if classifier == nil {
+		return false
+	}
+
+	value = strings.Trim(value, " \t\"'`")
+	if len(value) == 0 {
+		return false
+	}
+
+	for _, pattern := range classifier.patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	if classifier.isInterpolation(value) {
+		return true
+	}
+
+	if classifier.repeatCharacters && isRepeatedCharacter(value) {
+		return true
+	}
+
+	return classifier.words[normalisePlaceholderWord(value)]
+}
+
+// isInterpolation checks if a value is made up only of variable references, e.g. ${USER}:${PASSWORD}, or is a single
+// whole value reference such as $PASSWORD or <password>
+func (classifier *placeholderClassifier) isInterpolation(value string) bool {
+	remainder := value
+	found := false
+	for _, syntax := range classifier.syntaxes {
+		if syntax.wholeValue {
+			if syntax.regex.MatchString(value) {
+				return true
+			}
+			continue
+		}
+
+		if syntax.regex.MatchString(remainder) {
+			remainder = syntax.regex.ReplaceAllString(remainder, "")
+			found = true
+		}
+	}
+
+	return found && !containsLetterOrDigit(remainder)
+}
+
+// isInsideInterpolation checks if part of a line is within a variable reference, e.g. secret in {{ .Values.secret }}
+func (classifier *placeholderClassifier) isInsideInterpolation(line string, startIndex int, endIndex int) bool {
+	if classifier == nil {
+		return false
+	}
+
+	for _, syntax := range classifier.syntaxes {
+		if syntax.wholeValue {
+			continue
+		}
+
+		for _, span := range syntax.regex.FindAllStringIndex(line, -1) {
+			if span[0] <= startIndex && endIndex <= span[1] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isRepeatedCharacter checks if a value is one character repeated, ignoring separators, e.g. xxxx-xxxx or ********
+func isRepeatedCharacter(value string) bool {
+	var repeated rune
+	count := 0
+	for _, ch := range value {
+		if strings.ContainsRune("-_.: ", ch) {
+			continue
+		}
+
+		if count > 0 && unicode.ToLower(ch) != repeated {
+			return false
+		}
+
+		repeated = unicode.ToLower(ch)
+		count++
+	}
+
+	return count >= 3
+}
+
+// normalisePlaceholderWord lower cases a value and removes separators, so that CHANGE_ME and change-me are the same
+func normalisePlaceholderWord(value string) string {
+	var builder strings.Builder
+	for _, ch := range strings.ToLower(value) {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			builder.WriteRune(ch)
+		}
+	}
+
+	return builder.String()
+}
+
+func containsLetterOrDigit(value string) bool {
+	for _, ch := range value {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			return true
+		}
+	}
+
+	return false
+}
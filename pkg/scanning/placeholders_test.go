@@ -0,0 +1,165 @@
+package scanning
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		line        string
+		placeholder bool
+	}{
+		{`password = "${DB_PASSWORD}"`, true},
+		{`password = "$DB_PASSWORD"`, true},
+		{`password = "#{Octopus.Variable}"`, true},
+		{`password = "{{ .Values.secret }}"`, true},
+		{`password = "$(DatabasePassword)"`, true},
+		{`password = "${{ secrets.DB_PASSWORD }}"`, true},
+		{`password = "%DB_PASSWORD%"`, true},
+		{`password = "${DB_USER}:${DB_PASSWORD}"`, true},
+		{`password = "changeme"`, true},
+		{`password = "CHANGE_ME"`, true},
+		{`password = "<your-token-here>"`, true},
+		{`password = "xxxxxxxx"`, true},
+		{`password = "XXXX-XXXX-XXXX"`, true},
+		{`password = "Tr0ub4dor&3"`, false},
+		{`password = "prefix-${DB_PASSWORD}"`, false},
+		{`password = "xxxxxxxy"`, false},
+		{`password = "$Tr0ub4dor"`, false},
+		{`password = "<Zx81kQ0pLm & more>"`, false},
+	}
+
+	scanner := &Scanner{
+		Patterns: []SearchPattern{
+			{Pattern: `password = "(?P<secret>[^"]+)"`, Kind: "Password"},
+		},
+	}
+
+	for _, test := range tests {
+		matches, err := scanner.CheckContent(context.Background(), test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if placeholder := len(matches) == 0; placeholder != test.placeholder {
+			t.Errorf("expected %s to be a placeholder: %t", test.line, test.placeholder)
+		}
+	}
+}
+
+func TestPlaceholdersInsideInterpolation(t *testing.T) {
+	tests := []struct {
+		line    string
+		ignored bool
+	}{
+		{`value: {{ .Values.secret }}`, true},
+		{`value: ${PREFIX_secret}`, true},
+		{`<add key="api_key" value="Zx81kQ0pLm"/>`, false},
+		{`<add name="db" connectionString="Server=db;password='Zx81kQ0pLm'"/>`, false},
+		{`password = '$Tr0ub4dor' $secret`, false},
+	}
+
+	scanner := &Scanner{
+		Patterns: []SearchPattern{
+			{Pattern: `secret|Zx81kQ0pLm|\$Tr0ub4dor`, Kind: "Test Secret"},
+		},
+	}
+
+	for _, test := range tests {
+		matches, err := scanner.CheckContent(context.Background(), test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ignored := len(matches) == 0; ignored != test.ignored {
+			t.Errorf("expected the match in %s to be ignored: %t", test.line, test.ignored)
+		}
+	}
+}
+
+func TestPlaceholderOptions(t *testing.T) {
+	scanner := &Scanner{
+		Patterns: []SearchPattern{
+			{Pattern: `password = "(?P<secret>[^"]+)"`, Kind: "Password"},
+		},
+	}
+
+	config, err := ParseRepositoryConfig([]byte(`{
+		"placeholders": {
+			"disabledRules": ["dictionary"],
+			"words": ["hunter2"],
+			"patterns": ["^fake-"]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner.ApplyRepositoryConfig(config)
+
+	tests := []struct {
+		line        string
+		placeholder bool
+	}{
+		{`password = "changeme"`, false},
+		{`password = "hunter2"`, true},
+		{`password = "fake-password"`, true},
+		{`password = "${DB_PASSWORD}"`, true},
+	}
+
+	for _, test := range tests {
+		matches, err := scanner.CheckContent(context.Background(), test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if placeholder := len(matches) == 0; placeholder != test.placeholder {
+			t.Errorf("expected %s to be a placeholder: %t", test.line, test.placeholder)
+		}
+	}
+}
+
+func TestRepositoryConfigLeavesSharedOptions(t *testing.T) {
+	// Options parsed from flags share their backing arrays between every scanner made from them
+	words := make([]string, 1, 4)
+	words[0] = "changeme"
+	disabledDetectors := make([]string, 0, 4)
+
+	first := &Scanner{
+		Placeholders: PlaceholderOptions{Words: words},
+		Detectors:    DetectorOptions{DisabledDetectors: disabledDetectors},
+	}
+	second := &Scanner{
+		Placeholders: PlaceholderOptions{Words: words},
+		Detectors:    DetectorOptions{DisabledDetectors: disabledDetectors},
+	}
+
+	first.ApplyRepositoryConfig(&RepositoryConfig{
+		Placeholders: &PlaceholderOptions{Words: []string{"hunter2"}},
+		Detectors:    &DetectorOptions{DisabledDetectors: []string{"kubernetes-secret"}},
+	})
+	second.ApplyRepositoryConfig(&RepositoryConfig{
+		Placeholders: &PlaceholderOptions{Words: []string{"letmein"}},
+		Detectors:    &DetectorOptions{},
+	})
+
+	if !reflect.DeepEqual(first.Placeholders.Words, []string{"changeme", "hunter2"}) {
+		t.Errorf("expected the first repository's words to be kept but got %v", first.Placeholders.Words)
+	}
+	if !reflect.DeepEqual(second.Placeholders.Words, []string{"changeme", "letmein"}) {
+		t.Errorf("expected the second repository's words to be kept but got %v", second.Placeholders.Words)
+	}
+	if len(second.Detectors.DisabledDetectors) != 0 || len(words[:cap(words)][1]) != 0 {
+		t.Errorf("expected the shared options to be left as they were")
+	}
+}
+
+func TestRepositoryConfigKeepsPlaceholdersDisabled(t *testing.T) {
+	scanner := &Scanner{Placeholders: PlaceholderOptions{Disabled: true}}
+	scanner.ApplyRepositoryConfig(&RepositoryConfig{Placeholders: &PlaceholderOptions{Words: []string{"hunter2"}}})
+
+	if !scanner.Placeholders.Disabled {
+		t.Errorf("expected a repository's config not to turn placeholders back on")
+	}
+}
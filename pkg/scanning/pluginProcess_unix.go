@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package scanning
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup makes a plugin the leader of a new process group, so that the processes it starts can be killed
+// along with it
+func startProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills a plugin and every process it started which is still in its process group
+func killProcessGroup(command *exec.Cmd) {
+	_ = syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+}
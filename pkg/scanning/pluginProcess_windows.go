@@ -0,0 +1,14 @@
+package scanning
+
+import (
+	"os/exec"
+)
+
+// startProcessGroup does nothing on Windows, where processes aren't grouped in the same way
+func startProcessGroup(command *exec.Cmd) {
+}
+
+// killProcessGroup kills only the plugin on Windows, so processes it started may keep running
+func killProcessGroup(command *exec.Cmd) {
+	_ = command.Process.Kill()
+}
@@ -0,0 +1,234 @@
+package scanning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// PluginProtocolVersion is sent to plugins with each request, so that they can reject requests they don't understand
+const PluginProtocolVersion = 1
+
+const (
+	defaultPluginTimeout = 10 * time.Second
+
+	// maxPluginOutput limits how much a plugin can write, so that a broken plugin can't use up Orca's memory
+	maxPluginOutput = 10 * 1024 * 1024
+)
+
+// DetectorPlugin is a detector which runs out of process, for detectors which can't be part of Orca itself. For each
+// file the plugin's executable is started, sent a pluginRequest as JSON on stdin, and must write a pluginResponse as
+// JSON to stdout and exit. A plugin which fails, times out or writes invalid output doesn't stop the scan, but the
+// file's scan is reported as incomplete.
+type DetectorPlugin struct {
+	Name    string
+	Command string
+	Args    []string
+
+	// Timeout is how long the plugin can take for each file, and defaults to 10 seconds
+	Timeout time.Duration
+}
+
+// ParseDetectorPlugin parses a plugin given as name=command or just command, in which case the plugin is named after
+// the command's file name
+func ParseDetectorPlugin(value string) (DetectorPlugin, error) {
+	name, command := "", value
+	if separator := strings.IndexByte(value, '='); separator >= 0 {
+		name, command = value[:separator], value[separator+1:]
+	}
+
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return DetectorPlugin{}, fmt.Errorf("invalid detector plugin \"%s\", expected name=command", value)
+	}
+
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(fields[0]), filepath.Ext(fields[0]))
+	}
+
+	return DetectorPlugin{Name: name, Command: fields[0], Args: fields[1:]}, nil
+}
+
+type pluginLine struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// pluginRequest is sent to a plugin. The path is empty for content which isn't a file, such as an issue's body, and
+// lines are numbered as they are in the file, which is not always consecutively when only a patch is scanned.
+type pluginRequest struct {
+	Version int          `json:"version"`
+	Path    string       `json:"path"`
+	Lines   []pluginLine `json:"lines"`
+}
+
+// pluginMatch is a match found by a plugin, in the shape of a LineMatch. StartIndex and EndIndex are byte offsets
+// within the line.
+type pluginMatch struct {
+	LineNumber  int      `json:"lineNumber"`
+	StartIndex  int      `json:"startIndex"`
+	EndIndex    int      `json:"endIndex"`
+	Kind        string   `json:"kind"`
+	Severity    Severity `json:"severity,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+	Location    string   `json:"location,omitempty"`
+}
+
+type pluginResponse struct {
+	Matches []pluginMatch `json:"matches"`
+}
+
+// limitedBuffer is a buffer which fails writes beyond its limit, which stops the plugin writing to it
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (buffer *limitedBuffer) Write(data []byte) (int, error) {
+	if buffer.Len()+len(data) > buffer.limit {
+		return 0, fmt.Errorf("output is larger than %d bytes", buffer.limit)
+	}
+
+	return buffer.Buffer.Write(data)
+}
+
+// run sends the lines to the plugin and returns the matches it found
+func (plugin *DetectorPlugin) run(ctx context.Context, request *pluginRequest) ([]pluginMatch, error) {
+	timeout := plugin.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxPluginOutput}
+	stderr := &limitedBuffer{limit: 4096}
+	command := exec.Command(plugin.Command, plugin.Args...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	if err := runInProcessGroup(pluginCtx, command); err != nil {
+		// The scan itself may have been cancelled, rather than the plugin running out of time
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(pluginCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %v", timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%v: %s", err, message)
+		}
+		return nil, err
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+
+	return response.Matches, nil
+}
+
+// runInProcessGroup runs a plugin, killing it and every process it started when the context is done. Killing only the
+// plugin isn't enough, as a process it started in the background could keep its output open and so keep Wait waiting.
+func runInProcessGroup(ctx context.Context, command *exec.Cmd) error {
+	startProcessGroup(command)
+	if err := command.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(command)
+		case <-done:
+		}
+	}()
+
+	return command.Wait()
+}
+
+// runPlugins runs each plugin over the scanned lines, returning their matches for each line in the same order as the
+// lines. The numbers of the lines are taken from the content lines they were scanned from. Plugins which fail are
+// returned as reasons the scan is incomplete.
+func runPlugins(
+	ctx context.Context,
+	plugins []DetectorPlugin,
+	filePath string,
+	lines []string,
+	contentLines []contentLine) ([][]Match, []string) {
+
+	result := make([][]Match, len(lines))
+	if len(plugins) == 0 || len(lines) == 0 {
+		return result, nil
+	}
+
+	request := &pluginRequest{Version: PluginProtocolVersion, Path: filePath}
+	lineIndexes := map[int]int{}
+	for i, line := range lines {
+		request.Lines = append(request.Lines, pluginLine{Number: contentLines[i].number, Text: line})
+		lineIndexes[contentLines[i].number] = i
+	}
+
+	var failures []string
+	for _, plugin := range plugins {
+		matches, err := plugin.run(ctx, request)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("detector plugin %s failed: %v", plugin.Name, err))
+			continue
+		}
+
+		invalid := 0
+		for _, found := range matches {
+			lineIndex, ok := lineIndexes[found.LineNumber]
+			if !ok || found.Kind == "" || found.StartIndex < 0 || found.StartIndex >= found.EndIndex ||
+				found.EndIndex > len(lines[lineIndex]) {
+				invalid++
+				continue
+			}
+
+			severity := found.Severity
+			if _, ok := severityRanks[severity]; !ok {
+				severity = SeverityMedium
+			}
+
+			line := lines[lineIndex]
+			startColumn := utf8.RuneCountInString(line[:found.StartIndex])
+			result[lineIndex] = append(result[lineIndex], Match{
+				StartIndex:  found.StartIndex,
+				EndIndex:    found.EndIndex,
+				StartColumn: startColumn,
+				EndColumn:   startColumn + utf8.RuneCountInString(line[found.StartIndex:found.EndIndex]),
+				value:       line[found.StartIndex:found.EndIndex],
+				Kind:        found.Kind,
+				Severity:    severity,
+				Remediation: found.Remediation,
+				Location:    found.Location,
+				Plugin:      plugin.Name,
+				specificity: maxSpecificity,
+			})
+		}
+
+		if invalid > 0 {
+			failures = append(failures, fmt.Sprintf("detector plugin %s returned %d invalid matches", plugin.Name, invalid))
+		}
+	}
+
+	return result, failures
+}
@@ -0,0 +1,160 @@
+package scanning
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPluginHelperProcess isn't a real test. It is run as a plugin by TestDetectorPlugins, and behaves as the mode in
+// ORCA_TEST_PLUGIN says.
+func TestPluginHelperProcess(t *testing.T) {
+	mode := os.Getenv("ORCA_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+
+	if mode == "sleep" {
+		time.Sleep(10 * time.Second)
+		os.Exit(0)
+	}
+
+	var request pluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		os.Exit(2)
+	}
+
+	switch mode {
+	case "find":
+		response := pluginResponse{}
+		for _, line := range request.Lines {
+			if index := strings.Index(line.Text, "acme_"); index >= 0 {
+				response.Matches = append(response.Matches, pluginMatch{
+					LineNumber: line.Number,
+					StartIndex: index,
+					EndIndex:   len(line.Text),
+					Kind:       "Acme Token",
+					Severity:   SeverityHigh,
+				})
+			}
+		}
+		response.Matches = append(response.Matches, pluginMatch{LineNumber: 99, StartIndex: 0, EndIndex: 1, Kind: "Bad"})
+		_ = json.NewEncoder(os.Stdout).Encode(response)
+	case "crash":
+		fmt.Fprintln(os.Stderr, "proprietary format not found")
+		os.Exit(1)
+	case "hang":
+		time.Sleep(10 * time.Second)
+	case "fork":
+		// The child shares the plugin's stdout, so the plugin's output isn't finished until the child exits too
+		child := exec.Command(os.Args[0], "-test.run=TestPluginHelperProcess")
+		child.Env = append(os.Environ(), "ORCA_TEST_PLUGIN=sleep")
+		child.Stdout = os.Stdout
+		if err := child.Start(); err != nil {
+			os.Exit(2)
+		}
+		time.Sleep(10 * time.Second)
+	case "garbage":
+		fmt.Println("not json")
+	}
+
+	os.Exit(0)
+}
+
+func TestDetectorPlugins(t *testing.T) {
+	// Only the plugin which hangs is given a short timeout, as the others can be slow to start under the race detector
+	tests := []struct {
+		mode       string
+		timeout    time.Duration
+		kinds      []string
+		plugins    []string
+		incomplete string
+	}{
+		{"find", 0, []string{"Acme Token"}, []string{"acme"}, "detector plugin acme returned 1 invalid matches"},
+		{"crash", 0, nil, nil, "detector plugin acme failed: exit status 1: proprietary format not found"},
+		{"hang", 200 * time.Millisecond, nil, nil, "detector plugin acme failed: timed out after 200ms"},
+		{"fork", 200 * time.Millisecond, nil, nil, "detector plugin acme failed: timed out after 200ms"},
+		{"garbage", 0, nil, nil, "detector plugin acme failed: invalid output"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			os.Setenv("ORCA_TEST_PLUGIN", test.mode)
+			defer os.Unsetenv("ORCA_TEST_PLUGIN")
+
+			scanner := &Scanner{
+				Plugins: []DetectorPlugin{{
+					Name:    "acme",
+					Command: os.Args[0],
+					Args:    []string{"-test.run=TestPluginHelperProcess"},
+					Timeout: test.timeout,
+				}},
+			}
+
+			start := time.Now()
+			result, err := scanner.ScanFileContent("config.ini", "user = orca\ntoken = acme_9f8e7d6c5b4a\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Processes the plugin started are killed with it, rather than the scan waiting for them to exit
+			if elapsed := time.Since(start); test.timeout > 0 && elapsed > 5*time.Second {
+				t.Errorf("expected the plugin to be killed after %v but the scan took %v", test.timeout, elapsed)
+			}
+
+			var kinds []string
+			var plugins []string
+			for _, match := range result.Matches {
+				kinds = append(kinds, match.Kind)
+				plugins = append(plugins, match.Plugin)
+				if match.LineNumber != 2 || match.value != "acme_9f8e7d6c5b4a" {
+					t.Errorf("unexpected match %+v", match)
+				}
+			}
+
+			if !reflect.DeepEqual(kinds, test.kinds) || !reflect.DeepEqual(plugins, test.plugins) {
+				t.Errorf("expected %v %v but got %v %v", test.kinds, test.plugins, kinds, plugins)
+			}
+
+			if len(result.IncompleteReasons) != 1 || !strings.HasPrefix(result.IncompleteReasons[0], test.incomplete) {
+				t.Errorf("expected the scan to be incomplete with %q but got %v", test.incomplete, result.IncompleteReasons)
+			}
+		})
+	}
+}
+
+func TestParseDetectorPlugin(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected DetectorPlugin
+	}{
+		{
+			"acme=/opt/plugins/acme --strict",
+			DetectorPlugin{Name: "acme", Command: "/opt/plugins/acme", Args: []string{"--strict"}},
+		},
+		{
+			"/opt/plugins/internal-tokens.py",
+			DetectorPlugin{Name: "internal-tokens", Command: "/opt/plugins/internal-tokens.py", Args: []string{}},
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := ParseDetectorPlugin(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("expected %+v but got %+v", test.expected, actual)
+		}
+	}
+
+	if _, err := ParseDetectorPlugin("acme="); err == nil {
+		t.Error("expected an error for a plugin without a command")
+	}
+}
@@ -0,0 +1,54 @@
+package scanning
+
+import "strings"
+
+// PreviewOptions configures how much of a matched value is shown in reports. At most a quarter of the value is ever
+// shown from each end, so short values are masked entirely.
+type PreviewOptions struct {
+	VisiblePrefix int
+	VisibleSuffix int
+	MaskCharacter rune
+}
+
+func DefaultPreviewOptions() PreviewOptions {
+	return PreviewOptions{
+		VisiblePrefix: 4,
+		VisibleSuffix: 4,
+		MaskCharacter: '*',
+	}
+}
+
+// maskValue masks all but the start and end of a value, e.g. AKIA************XQ7Z
+func maskValue(value string, options PreviewOptions) string {
+	runes := []rune(value)
+	maxVisible := len(runes) / 4
+
+	prefix := minInt(options.VisiblePrefix, maxVisible)
+	suffix := minInt(options.VisibleSuffix, maxVisible)
+	if prefix < 0 {
+		prefix = 0
+	}
+	if suffix < 0 {
+		suffix = 0
+	}
+
+	maskCharacter := options.MaskCharacter
+	if maskCharacter == 0 {
+		maskCharacter = '*'
+	}
+
+	var builder strings.Builder
+	builder.WriteString(string(runes[:prefix]))
+	builder.WriteString(strings.Repeat(string(maskCharacter), len(runes)-prefix-suffix))
+	builder.WriteString(string(runes[len(runes)-suffix:]))
+
+	return builder.String()
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
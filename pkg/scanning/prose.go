@@ -0,0 +1,291 @@
+package scanning
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ProseCredentialKind is the kind of credentials disclosed in a sentence, e.g. "my password is Tr0ub4dor&3"
+const ProseCredentialKind = "Credential in prose"
+
+const proseCredentialRemediation = "Rotate the credential. It was readable by anyone who could see the text, and " +
+	"may already have been sent out in notification emails."
+
+// markdownRegionKind is the kind of Markdown syntax a part of a line is in
+type markdownRegionKind int
+
+const (
+	inlineCodeRegion markdownRegionKind = iota
+	linkTargetRegion
+)
+
+type markdownRegion struct {
+	kind       markdownRegionKind
+	startIndex int
+	endIndex   int
+}
+
+// markdownLine is the Markdown structure of a line of prose
+type markdownLine struct {
+	codeBlock bool
+	quoted    bool
+	regions   []markdownRegion
+}
+
+var (
+	markdownQuoteRegex = regexp.MustCompile(`^ {0,3}(?:> ?)+`)
+	markdownFenceRegex = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+
+	// Link targets are inline links and images, with or without angle brackets, reference definitions, autolinks and
+	// bare URLs. The bare URLs are the last group.
+	markdownLinkTargetRegex = regexp.MustCompile(`\]\(\s*(?:<([^>]*)>|([^\s()]*(?:\([^\s()]*\)[^\s()]*)*))|` +
+		`^ {0,3}\[[^\]]+\]:\s*(\S+)|<([a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]+)>|\b((?:https?|ftp)://[^\s<>()\[\]]+)`)
+)
+
+// markdownLexer finds the code blocks, quoted replies, inline code and link targets in Markdown one line at a time,
+// carrying fenced code blocks between lines
+type markdownLexer struct {
+	fence string
+}
+
+func (lexer *markdownLexer) lexLine(line string) markdownLine {
+	quote := markdownQuoteRegex.FindString(line)
+	result := markdownLine{quoted: quote != ""}
+	content := line[len(quote):]
+
+	if lexer.fence != "" {
+		result.codeBlock = true
+		if strings.HasPrefix(strings.TrimSpace(content), lexer.fence) {
+			lexer.fence = ""
+		}
+		return result
+	}
+
+	if opening := markdownFenceRegex.FindStringSubmatch(content); opening != nil {
+		result.codeBlock = true
+		lexer.fence = opening[1]
+		return result
+	}
+
+	result.regions = lexMarkdownInline(line, len(quote))
+	return result
+}
+
+// lexMarkdownInline finds the inline code spans and link targets in a line, from the start of its content
+func lexMarkdownInline(line string, start int) []markdownRegion {
+	var regions []markdownRegion
+	for i := start; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		// A code span closes with a run of backticks the same length as the one that opened it
+		run := i
+		for run < len(line) && line[run] == '`' {
+			run++
+		}
+		delimiter := line[i:run]
+		closing := strings.Index(line[run:], delimiter)
+		if closing < 0 {
+			i = run
+			continue
+		}
+
+		closing += run
+		regions = append(regions, markdownRegion{kind: inlineCodeRegion, startIndex: run, endIndex: closing})
+		i = closing + len(delimiter)
+	}
+
+	// Links in code spans aren't links
+	for _, indices := range markdownLinkTargetRegex.FindAllStringSubmatchIndex(line, -1) {
+		bareURLGroup := len(indices)/2 - 1
+		for group := 1; group <= bareURLGroup; group++ {
+			startIndex, endIndex := indices[group*2], indices[group*2+1]
+			if startIndex < start || startIndex == endIndex || markdownRegionAt(regions, startIndex, endIndex) != nil {
+				continue
+			}
+
+			// Punctuation after a bare URL usually ends the sentence rather than the URL
+			if group == bareURLGroup {
+				endIndex = startIndex + len(strings.TrimRight(line[startIndex:endIndex], ".,;:!?'\""))
+			}
+			regions = append(regions, markdownRegion{kind: linkTargetRegion, startIndex: startIndex, endIndex: endIndex})
+		}
+	}
+
+	return regions
+}
+
+// markdownRegionAt returns the region a range is within, if there is one
+func markdownRegionAt(regions []markdownRegion, startIndex int, endIndex int) *markdownRegion {
+	for i := range regions {
+		if startIndex >= regions[i].startIndex && endIndex <= regions[i].endIndex {
+			return &regions[i]
+		}
+	}
+
+	return nil
+}
+
+// withoutLinkBrackets replaces the angle brackets around link targets with spaces, so that an autolink such as
+// <https://example.com> isn't mistaken for an angle bracket placeholder
+func (line *markdownLine) withoutLinkBrackets(text string) string {
+	masked := []byte(text)
+	for _, region := range line.regions {
+		if region.kind == linkTargetRegion && region.startIndex > 0 && region.endIndex < len(text) &&
+			text[region.startIndex-1] == '<' && text[region.endIndex] == '>' {
+			masked[region.startIndex-1] = ' '
+			masked[region.endIndex] = ' '
+		}
+	}
+
+	return string(masked)
+}
+
+func (line *markdownLine) describe(region *markdownRegion) string {
+	var description string
+	switch {
+	case line.codeBlock:
+		description = "in a code block"
+	case region != nil && region.kind == inlineCodeRegion:
+		description = "in inline code"
+	case region != nil && region.kind == linkTargetRegion:
+		description = "in a link"
+	}
+
+	if line.quoted {
+		if description == "" {
+			return "in a quoted reply"
+		}
+		return description + " in a quoted reply"
+	}
+
+	return description
+}
+
+// filterMatchesToProse drops matches in harmless link targets, and describes where the rest are in the Markdown. A link
+// target is only harmless if the match isn't in a password or a credential parameter within it.
+func filterMatchesToProse(matches []Match, line string, markdown *markdownLine) []Match {
+	var result []Match
+	for _, match := range matches {
+		region := markdownRegionAt(markdown.regions, match.StartIndex, match.EndIndex)
+		if region != nil && region.kind == linkTargetRegion &&
+			!inURLCredential(line[region.startIndex:region.endIndex],
+				match.StartIndex-region.startIndex, match.EndIndex-region.startIndex) {
+			continue
+		}
+
+		match.CodeContext = markdown.describe(region)
+		result = append(result, match)
+	}
+
+	return result
+}
+
+var (
+	urlUserInfoRegex        = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#@\s]*:([^/?#@\s]+)@`)
+	urlParameterRegex       = regexp.MustCompile(`[?&;]([^=&;#]+)=([^&;#]*)`)
+	credentialParameterName = regexp.MustCompile(`(?i)pass|pwd|secret|token|key|auth|sig|credential|session`)
+)
+
+// inURLCredential checks whether a range within a URL overlaps its password or the value of a parameter whose name
+// suggests it holds a credential
+func inURLCredential(url string, startIndex int, endIndex int) bool {
+	overlaps := func(start int, end int) bool {
+		return start < endIndex && startIndex < end
+	}
+
+	if userInfo := urlUserInfoRegex.FindStringSubmatchIndex(url); userInfo != nil && overlaps(userInfo[2], userInfo[3]) {
+		return true
+	}
+
+	for _, parameter := range urlParameterRegex.FindAllStringSubmatchIndex(url, -1) {
+		if credentialParameterName.MatchString(url[parameter[2]:parameter[3]]) && overlaps(parameter[4], parameter[5]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proseCredentialRegex finds a credential named in a sentence and the value that follows it, e.g. "the token is X",
+// "my password for staging is now X" or "login: admin / pass: X"
+var proseCredentialRegex = regexp.MustCompile(`(?i)\b(?:password|passwd|passphrase|passcode|pwd|pass|pin|` +
+	`(?:api|access|secret|private)[ _-]?key|token|secret|credentials?)` +
+	`(?:\s+(?:for|to|on|of)\s+(?:the\s+)?[\w.-]+)?` +
+	`\s*(?:\bis\b|\bwas\b|:|=|->|-)\s*(?:(?:now|still|just|set\s+to|changed\s+to)\s+)?(\S+)`)
+
+// findProseCredentials finds credentials disclosed in the sentences of a line of prose. Only values which look like
+// credentials are kept, so that "the token is invalid" isn't reported.
+func findProseCredentials(line string, markdown *markdownLine) []Match {
+	if markdown.codeBlock {
+		return nil
+	}
+
+	var matches []Match
+	for _, indices := range proseCredentialRegex.FindAllStringSubmatchIndex(line, -1) {
+		startIndex, endIndex := indices[2], indices[3]
+		value := line[startIndex:endIndex]
+
+		// Values are often quoted, in code spans or at the end of a sentence
+		trimmed := strings.TrimLeft(value, "\"'`*_(")
+		startIndex += len(value) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, "\"'`*_).,;:")
+		endIndex = startIndex + len(trimmed)
+
+		region := markdownRegionAt(markdown.regions, startIndex, endIndex)
+		if !looksLikeProseCredential(trimmed) || (region != nil && region.kind == linkTargetRegion) {
+			continue
+		}
+
+		startColumn := utf8.RuneCountInString(line[:startIndex])
+		matches = append(matches, Match{
+			StartIndex:  startIndex,
+			EndIndex:    endIndex,
+			StartColumn: startColumn,
+			EndColumn:   startColumn + utf8.RuneCountInString(trimmed),
+			value:       trimmed,
+			Kind:        ProseCredentialKind,
+			Severity:    SeverityHigh,
+			Remediation: proseCredentialRemediation,
+			CodeContext: markdown.describe(region),
+		})
+	}
+
+	return matches
+}
+
+// looksLikeProseCredential checks a value is long enough and mixes enough kinds of character to be a credential
+// rather than a word
+func looksLikeProseCredential(value string) bool {
+	if utf8.RuneCountInString(value) < 6 || strings.Contains(value, "://") {
+		return false
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, ch := range value {
+		switch {
+		case unicode.IsLower(ch):
+			lower = true
+		case unicode.IsUpper(ch):
+			upper = true
+		case unicode.IsDigit(ch):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	// A capitalised word or a hyphenated word is still just a word
+	return classes >= 3 || (classes == 2 && digit)
+}
@@ -0,0 +1,151 @@
+package scanning
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxReadLineLength caps how much of a single line is kept when the scan budget doesn't already limit it
+const maxReadLineLength = 10 * 1024 * 1024
+
+// MatchStream is a scan of content read from a reader. Matches are sent on Matches as they are found, and the channel
+// is closed when the scan ends.
+type MatchStream struct {
+	matches           chan LineMatch
+	err               error
+	incompleteReasons []string
+}
+
+func (stream *MatchStream) Matches() <-chan LineMatch {
+	return stream.matches
+}
+
+// Err is why the scan stopped before the end of the content, either because the reader failed or the context was
+// cancelled. It is only set once Matches has been closed.
+func (stream *MatchStream) Err() error {
+	return stream.err
+}
+
+// IncompleteReasons is why the scan was incomplete if it ran out of budget, as for ScanContent. It is only set once
+// Matches has been closed.
+func (stream *MatchStream) IncompleteReasons() []string {
+	return stream.incompleteReasons
+}
+
+// ScanReader scans content from a reader, such as stdin or an entry in an archive, see ScanFileReader
+func (scanner *Scanner) ScanReader(ctx context.Context, reader io.Reader) (*MatchStream, error) {
+	return scanner.ScanFileReader(ctx, "", reader)
+}
+
+// ScanFileReader scans a file's content from a reader, finding the same matches as ScanFileContent. Lines are read one
+// at a time, and each line's pattern and honeytoken matches are sent before the next line is read. Detectors and
+// plugins need to see many lines at once, so lines are held for them up to MaxFileBytes, or the default budget's if
+// there is no limit, and their matches are sent once those lines have all been read. A match they find which overlaps
+// one already sent is not sent again. Once the budget runs out, the rest of the content is only read if there are
+// honeytokens to look for. No more than maxReadLineLength bytes of a line are ever kept.
+func (scanner *Scanner) ScanFileReader(ctx context.Context, path string, reader io.Reader) (*MatchStream, error) {
+	scan, err := scanner.newContentScan(ctx, path, false)
+	if err != nil {
+		return nil, err
+	}
+	scan.streaming = true
+
+	stream := &MatchStream{matches: make(chan LineMatch)}
+	go func() {
+		defer close(stream.matches)
+		stream.err = scan.readLines(ctx, bufio.NewReader(reader), stream.matches)
+
+		matches, reasons := scan.finish()
+		if stream.err == nil {
+			stream.err = sendMatches(ctx, matches, stream.matches)
+		}
+		stream.incompleteReasons = reasons
+	}()
+
+	return stream, nil
+}
+
+// readLines adds each line from the reader to the scan, splitting the content on new lines the same way as the string
+// API. Lines longer than the whole scan budget can't be scanned, so unless they need to be searched for honeytokens,
+// only the start of them is kept. Otherwise lines are cut off at maxReadLineLength, which makes the scan incomplete.
+func (scan *contentScan) readLines(ctx context.Context, reader *bufio.Reader, matches chan<- LineMatch) error {
+	maxLineLength := maxReadLineLength
+	budget := scan.state.budget
+	if len(scan.honeytokens) == 0 && budget.MaxFileBytes > 0 && budget.MaxFileBytes < maxReadLineLength {
+		maxLineLength = budget.MaxFileBytes + 1
+	}
+
+	for lineNumber := 1; scan.needsLines(); lineNumber++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, truncated, err := readLine(reader, maxLineLength)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if truncated && maxLineLength == maxReadLineLength {
+			scan.streamReasons = append(scan.streamReasons, fmt.Sprintf(
+				"line %d is longer than %d bytes, only the start of it was scanned",
+				lineNumber,
+				maxReadLineLength))
+		}
+
+		if sendErr := sendMatches(ctx, scan.addLine(contentLine{number: lineNumber, text: line}), matches); sendErr != nil {
+			return sendErr
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+
+	// The scan also stops needing lines if it is cancelled while adding one
+	return ctx.Err()
+}
+
+// readLine reads up to the next new line, keeping at most maxLength bytes of the line if maxLength is set, and
+// reporting whether the rest of the line was dropped. At the end of the content it returns the last line, which may be
+// empty, with io.EOF.
+func readLine(reader *bufio.Reader, maxLength int) (string, bool, error) {
+	var line strings.Builder
+	truncated := false
+	for {
+		fragment, err := reader.ReadSlice('\n')
+		if err == nil {
+			fragment = fragment[:len(fragment)-1]
+		}
+
+		if keep := maxLength - line.Len(); maxLength > 0 && len(fragment) > keep {
+			line.Write(fragment[:keep])
+			truncated = true
+		} else {
+			line.Write(fragment)
+		}
+
+		switch err {
+		case nil:
+			return line.String(), truncated, nil
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return line.String(), truncated, err
+		}
+	}
+}
+
+func sendMatches(ctx context.Context, matches []LineMatch, channel chan<- LineMatch) error {
+	for _, match := range matches {
+		select {
+		case channel <- match:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package scanning
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanReader(t *testing.T) {
+	content := strings.Join([]string{
+		"apiVersion: v1",
+		"kind: Secret",
+		"data:",
+		"  password: aHVudGVyMg==",
+		`const token = "tok_123456"; // tok_654321`,
+		"aws_access_key_id = AKIAZQ3DR5CANARY0001\r",
+		strings.Repeat("tok_999999 ", 50),
+		"tok_000000",
+		"",
+	}, "\n")
+
+	tests := []struct {
+		name    string
+		path    string
+		scanner Scanner
+	}{
+		{
+			name:    "matched line by line",
+			path:    "main.js",
+			scanner: Scanner{Detectors: DetectorOptions{Disabled: true}},
+		},
+		{
+			name:    "held back for the detectors",
+			scanner: Scanner{},
+		},
+		{
+			name:    "over the byte budget",
+			scanner: Scanner{Budget: ScanBudget{MaxFileBytes: 200}, Detectors: DetectorOptions{Disabled: true}},
+		},
+		{
+			name: "honeytokens over the byte budget",
+			scanner: Scanner{
+				Budget:      ScanBudget{MaxFileBytes: 100},
+				Honeytokens: []Honeytoken{{Name: "canary", Fingerprint: HoneytokenFingerprint("tok_000000")}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := test.scanner
+			scanner.Patterns = []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}, {Pattern: `AKIA[0-9A-Z]{16}`, Kind: "AWS"}}
+
+			expected, err := scanner.ScanFileContent(test.path, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stream, err := scanner.ScanFileReader(context.Background(), test.path, strings.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var matches []LineMatch
+			for match := range stream.Matches() {
+				matches = append(matches, match)
+			}
+
+			if stream.Err() != nil {
+				t.Fatal(stream.Err())
+			}
+
+			// Matches from the detectors are sent after the lines they need have been read, so only the order of the
+			// lines is the same as the string API's
+			sortLineMatches(matches)
+			sortLineMatches(expected.Matches)
+			if len(matches) == 0 || !reflect.DeepEqual(matches, expected.Matches) {
+				t.Errorf("expected %+v but got %+v", expected.Matches, matches)
+			}
+			if !reflect.DeepEqual(stream.IncompleteReasons(), expected.IncompleteReasons) {
+				t.Errorf("expected %v but got %v", expected.IncompleteReasons, stream.IncompleteReasons())
+			}
+		})
+	}
+}
+
+func TestScanReaderCancellation(t *testing.T) {
+	scanner := &Scanner{
+		Patterns:  []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}},
+		Detectors: DetectorOptions{Disabled: true},
+	}
+
+	// The writer never finishes, so only cancelling can end the scan
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	go func() {
+		for {
+			if _, err := writer.Write([]byte("tok_123456\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := scanner.ScanReader(ctx, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if match := <-stream.Matches(); match.LineNumber != 1 {
+		t.Errorf("expected the first line's match but got %+v", match)
+	}
+
+	cancel()
+	for range stream.Matches() {
+	}
+
+	if stream.Err() != context.Canceled {
+		t.Errorf("expected the scan to be cancelled but got %v", stream.Err())
+	}
+}
+
+func TestScanReaderBeforeEOF(t *testing.T) {
+	scanner := &Scanner{
+		Patterns:    []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}},
+		Honeytokens: []Honeytoken{{Name: "canary", Fingerprint: HoneytokenFingerprint("tok_000000")}},
+	}
+
+	// The writer never finishes, so matches can only arrive if they are sent before the end of the content, even
+	// though the detectors are enabled
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	go func() {
+		for {
+			if _, err := writer.Write([]byte("tok_123456 tok_000000\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := scanner.ScanReader(ctx, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case match := <-stream.Matches():
+		if match.LineNumber != 1 {
+			t.Errorf("expected the first line's match but got %+v", match)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected a match before the end of the content")
+	}
+
+	cancel()
+	for range stream.Matches() {
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	reader := strings.NewReader("short\n" + strings.Repeat("x", 10000) + "\nlast")
+
+	// A small buffer splits the long line into many fragments
+	buffered := bufio.NewReaderSize(reader, 16)
+
+	for _, expected := range []struct {
+		line      string
+		truncated bool
+		err       error
+	}{
+		{"short", false, nil},
+		{strings.Repeat("x", 100), true, nil},
+		{"last", false, io.EOF},
+	} {
+		line, truncated, err := readLine(buffered, 100)
+		if line != expected.line || truncated != expected.truncated || err != expected.err {
+			t.Errorf("expected %q %t %v but got %q %t %v",
+				expected.line, expected.truncated, expected.err, line, truncated, err)
+		}
+	}
+}
+
+func sortLineMatches(matches []LineMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].LineNumber != matches[j].LineNumber {
+			return matches[i].LineNumber < matches[j].LineNumber
+		}
+		return matches[i].StartIndex < matches[j].StartIndex
+	})
+}
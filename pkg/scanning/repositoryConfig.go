@@ -0,0 +1,66 @@
+package scanning
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RepositoryConfigPath is where a repository can configure how it is scanned
+const RepositoryConfigPath = ".orca.json"
+
+// RepositoryConfig holds the scanning settings a repository has chosen for itself
+type RepositoryConfig struct {
+	Placeholders *PlaceholderOptions `json:"placeholders,omitempty"`
+	Detectors    *DetectorOptions    `json:"detectors,omitempty"`
+	Triage       *TriageOptions      `json:"triage,omitempty"`
+}
+
+func ParseRepositoryConfig(data []byte) (*RepositoryConfig, error) {
+	var config RepositoryConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", RepositoryConfigPath, err)
+	}
+
+	if config.Placeholders != nil {
+		for _, pattern := range config.Placeholders.Patterns {
+			if _, err := compilePattern(pattern); err != nil {
+				return nil, fmt.Errorf("invalid placeholder pattern \"%s\" in %s: %v", pattern, RepositoryConfigPath, err)
+			}
+		}
+	}
+
+	if config.Detectors != nil {
+		if err := config.Detectors.Validate(); err != nil {
+			return nil, fmt.Errorf("%v in %s", err, RepositoryConfigPath)
+		}
+	}
+
+	return &config, nil
+}
+
+// ApplyRepositoryConfig adds a repository's settings to the scanner's own. The scanner's settings are copied rather
+// than appended to in place, as they may share their backing arrays with the options of every other scanner.
+func (scanner *Scanner) ApplyRepositoryConfig(config *RepositoryConfig) {
+	if config.Placeholders != nil {
+		scanner.Placeholders.Disabled = scanner.Placeholders.Disabled || config.Placeholders.Disabled
+		scanner.Placeholders.DisabledRules = appendCopy(scanner.Placeholders.DisabledRules, config.Placeholders.DisabledRules)
+		scanner.Placeholders.Words = appendCopy(scanner.Placeholders.Words, config.Placeholders.Words)
+		scanner.Placeholders.Patterns = appendCopy(scanner.Placeholders.Patterns, config.Placeholders.Patterns)
+	}
+
+	if config.Detectors != nil {
+		scanner.Detectors.Disabled = scanner.Detectors.Disabled || config.Detectors.Disabled
+		scanner.Detectors.DisabledDetectors = appendCopy(
+			scanner.Detectors.DisabledDetectors,
+			config.Detectors.DisabledDetectors)
+	}
+
+	if config.Triage != nil && config.Triage.Disabled {
+		scanner.Triage = nil
+	}
+}
+
+// appendCopy appends values to a copy of base, leaving base's backing array untouched
+func appendCopy(base []string, values []string) []string {
+	return append(append([]string(nil), base...), values...)
+}
@@ -1,12 +1,17 @@
 package scanning
 
 import (
+	"Orca/pkg/caching"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/google/go-github/v33/github"
 	"log"
+	"regexp"
 	"strings"
-	"Orca/pkg/caching"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 type FileContentMatch struct {
@@ -19,19 +24,134 @@ type LineMatch struct {
 	Match
 }
 
+// Match holds the position of a matched value within its line. StartIndex and EndIndex are byte offsets, while
+// StartColumn and EndColumn count runes and should be used when editing text as characters.
 type Match struct {
-	StartIndex int
-	EndIndex   int
-	value      string
-	Kind       string
-	Resolved   bool
+	StartIndex  int
+	EndIndex    int
+	StartColumn int
+	EndColumn   int
+	value       string
+	Kind        string
+	Severity    Severity
+	Resolved    bool
+
+	// Remediation describes how to deal with the secret, if it was found by a built-in detector or is a honeytoken
+	Remediation string
+
+	// Location names where the value is within a structured file, e.g. Secret/db-creds data.password, and
+	// decodedValue is the value after decoding it if it was encoded in the file
+	Location     string
+	decodedValue string
+
+	// CodeContext describes where the value is in a source file, e.g. string assigned to apiKey, or in prose, e.g. in
+	// inline code. Matches of patterns with the code scope are dropped when they are in comments or identifiers.
+	CodeContext string
+	codeOnly    bool
+	codeRegion  *codeRegion
+
+	// Honeytoken is the name of the canary credential in the honeytoken registry which the value matched
+	Honeytoken string
+
+	// Plugin is the name of the detector plugin which found the match, if it was found by one
+	Plugin string
+
+	// Triage is the triage model's score for the match, or nil if the model is turned off or not yet trained
+	Triage *TriageScore
+
+	// SecondaryKinds are the kinds of other patterns which matched the same value
+	SecondaryKinds []string
+	specificity    int
+
+	// Preview is the value with all but its start and end masked, so that it is safe to show in reports
+	Preview string
+	Length  int
+
+	// When a pattern has a secret capture group, the value is only that group and the context is the whole match.
+	// ContextPreview is the context with the value masked.
+	context        string
+	contextOffset  int
+	ContextPreview string
 }
 
 type Scanner struct {
-	Patterns []SearchPattern
+	Patterns     []SearchPattern
+	Budget       ScanBudget
+	DiffMode     bool
+	Preview      PreviewOptions
+	Placeholders PlaceholderOptions
+	Detectors    DetectorOptions
+	ScanAllFiles bool
+
+	// Honeytokens are checked for in every line, and can't be turned off or filtered out by a repository's settings
+	Honeytokens []Honeytoken
+
+	Plugins []DetectorPlugin
+
+	// Triage scores matches from past triage decisions, and is nil when it is turned off
+	Triage *TriageModel
+
+	// Workers is how many files from a list of commits are fetched and scanned at once
+	Workers int
+}
+
+type ScannerOptions struct {
+	Budget       ScanBudget
+	Preview      PreviewOptions
+	Placeholders PlaceholderOptions
+	Detectors    DetectorOptions
+
+	// DiffMode scans only the lines added by a commit when the file query includes the commit's patch
+	DiffMode bool
+
+	// ScanAllFiles scans vendored, generated and minified files, which are skipped by default
+	ScanAllFiles bool
+
+	// Honeytokens is the registry of canary credentials, if there is one
+	Honeytokens HoneytokenStore
+
+	// Plugins are detectors which run out of process
+	Plugins []DetectorPlugin
+
+	// TriageFile holds the triage decisions the triage model is trained on. The model is off if it is empty.
+	TriageFile string
+
+	// Workers is how many files from a list of commits are fetched and scanned at once
+	Workers int
+}
+
+// ContentScanResult holds the matches found in a piece of content, along with the reasons the scan was incomplete if
+// it ran out of budget
+type ContentScanResult struct {
+	Matches           []LineMatch
+	IncompleteReasons []string
+}
+
+func (result *ContentScanResult) IsIncomplete() bool {
+	return len(result.IncompleteReasons) > 0
+}
+
+// IncompleteScan describes a file which could not be scanned in full
+type IncompleteScan struct {
+	Path         string
+	PermalinkURL string
+	Reasons      []string
+}
+
+type FileScanResult struct {
+	Matches    []FileContentMatch
+	Incomplete *IncompleteScan
+	Skipped    *SkippedFile
 }
 
-func NewScanner(patternStore *PatternStore) (*Scanner, error) {
+type compiledPattern struct {
+	SearchPattern
+	regex       *regexp.Regexp
+	secretGroup int
+	specificity int
+}
+
+func NewScanner(patternStore *PatternStore, options ScannerOptions) (*Scanner, error) {
 
 	patterns, err := (*patternStore).GetPatterns()
 	if err != nil {
@@ -39,18 +159,51 @@ func NewScanner(patternStore *PatternStore) (*Scanner, error) {
 	}
 
 	scanner := &Scanner{
-		Patterns: patterns,
+		Patterns:     patterns,
+		Budget:       options.Budget,
+		DiffMode:     options.DiffMode,
+		Preview:      options.Preview,
+		Placeholders: options.Placeholders,
+		Detectors:    options.Detectors,
+		ScanAllFiles: options.ScanAllFiles,
+		Plugins:      options.Plugins,
+		Workers:      options.Workers,
+	}
+
+	if len(options.TriageFile) > 0 {
+		decisions, err := LoadTriageDecisions(options.TriageFile)
+		if err != nil {
+			return nil, err
+		}
+		scanner.Triage = TrainTriageModel(decisions)
+	}
+
+	if options.Honeytokens != nil {
+		if scanner.Honeytokens, err = options.Honeytokens.GetHoneytokens(); err != nil {
+			return nil, err
+		}
 	}
 
 	return scanner, nil
 }
 
+// CheckFileContentFromQueries scans the files changed by a list of commits, which are in the order the commits were
+// made. The files are fetched and scanned by a pool of workers, then their results are combined in the order of the
+// queries, so that a later commit can resolve the matches from an earlier one. If the context is cancelled or reaches
+// its deadline part way through, the results so far are returned, with the files which were left unscanned marked as
+// incomplete.
 func (scanner *Scanner) CheckFileContentFromQueries(
+	ctx context.Context,
 	githubClient *github.Client,
 	fileQueries []caching.GitHubFileQuery) ([]CommitScanResult, error) {
 
+	fileQueryScans, err := scanner.scanFileQueries(ctx, githubClient, fileQueries)
+	if err != nil {
+		return nil, err
+	}
+
 	var commitScanResults []CommitScanResult
-	for _, fileQuery := range fileQueries {
+	for i, fileQuery := range fileQueries {
 
 		// NOTE: ListCommits does not include any references to which files were changed (commit.Files is always nil),
 		//	so we need to send another request specifically for the commit
@@ -59,14 +212,7 @@ func (scanner *Scanner) CheckFileContentFromQueries(
 
 		// If the file was removed, then mark any previous matches as resolved
 		if fileQuery.Status == caching.FileRemoved {
-			for i, previousScanResult := range commitScanResults {
-				for j, previousFileMatch := range previousScanResult.Matches {
-					if previousFileMatch.Path == fileQuery.FileName {
-						commitScanResults[i].Matches[j].Resolved = true
-					}
-				}
-			}
-
+			resolveMatches(commitScanResults, fileQuery.FileName, nil)
 			continue
 		}
 
@@ -75,35 +221,41 @@ func (scanner *Scanner) CheckFileContentFromQueries(
 			continue
 		}
 
-		log.Printf("Checking %s from %s", fileQuery.FileName, fileQuery.CommitSHA)
+		fileScanResult := fileQueryScans[i].result
+		if fileScanResult == nil {
+			commitScanResults = append(commitScanResults, cancelledScanResult(fileQuery, ctx.Err()))
+			continue
+		}
 
-		fileContentMatches, err := scanner.CheckFileContentFromQuery(githubClient, fileQuery)
-		if err != nil {
-			return nil, err
+		// Previous matches in this file are resolved if the patch removed them
+		patch := fileQueryScans[i].patch
+		if patch != nil {
+			resolveMatches(commitScanResults, fileQuery.FileName, func(match FileContentMatch) bool {
+				return patch.removedValue(match.value)
+			})
 		}
 
-		if len(fileContentMatches) > 0 {
+		if fileScanResult.Skipped != nil {
+			log.Printf("Skipping %s from %s: %s", fileQuery.FileName, fileQuery.CommitSHA, fileScanResult.Skipped.Reason)
+			commitScanResult.Skipped = append(commitScanResult.Skipped, *fileScanResult.Skipped)
+			commitScanResult.Matches = unknownMatches(commitScanResults, fileScanResult.Matches)
+			commitScanResults = append(commitScanResults, commitScanResult)
+			continue
+		}
 
-			// Ignore previously known matches
-			for _, fileContentMatch := range fileContentMatches {
-				if !MatchIsKnown(getMatches(commitScanResults), fileContentMatch) {
-					commitScanResult.Matches = append(commitScanResult.Matches, fileContentMatch)
-				}
-			}
+		if fileScanResult.Incomplete != nil {
+			commitScanResult.Incomplete = append(commitScanResult.Incomplete, *fileScanResult.Incomplete)
+		}
 
-		} else {
+		if len(fileScanResult.Matches) > 0 {
+			commitScanResult.Matches = unknownMatches(commitScanResults, fileScanResult.Matches)
+		} else if fileScanResult.Incomplete == nil && patch == nil {
 
 			// No matches found, previous matches in this file should be resolved
-			for i, previousScanResult := range commitScanResults {
-				for j, previousFileMatch := range previousScanResult.Matches {
-					if previousFileMatch.Path == fileQuery.FileName {
-						commitScanResults[i].Matches[j].Resolved = true
-					}
-				}
-			}
+			resolveMatches(commitScanResults, fileQuery.FileName, nil)
 		}
 
-		if commitScanResult.HasMatches() {
+		if commitScanResult.HasMatches() || commitScanResult.IsIncomplete() {
 			commitScanResults = append(commitScanResults, commitScanResult)
 		}
 	}
@@ -111,9 +263,142 @@ func (scanner *Scanner) CheckFileContentFromQueries(
 	return commitScanResults, nil
 }
 
+// fileQueryScan is what was found in a single added or modified file, before it is combined with the other files. The
+// patch is set if only the lines it added were scanned.
+type fileQueryScan struct {
+	result *FileScanResult
+	patch  *filePatch
+}
+
+// scanFileQueries fetches and scans the added and modified files with a pool of workers. The scans are returned in the
+// same order as the queries, and are empty for the files which weren't scanned before the context was cancelled. The
+// first error stops the rest of the workers.
+func (scanner *Scanner) scanFileQueries(
+	ctx context.Context,
+	githubClient *github.Client,
+	fileQueries []caching.GitHubFileQuery) ([]fileQueryScan, error) {
+
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fileQueryScans := make([]fileQueryScan, len(fileQueries))
+	skippers := &fileSkippers{byCommit: map[string]*fileSkipper{}}
+	var failure error
+	var failureOnce sync.Once
+
+	queryIndexes := make(chan int)
+	var workers sync.WaitGroup
+	for worker := 0; worker < scanner.workerCount(); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range queryIndexes {
+				fileQueryScan, err := scanner.scanFileQuery(poolCtx, githubClient, fileQueries[i], skippers)
+				if err == nil {
+					fileQueryScans[i] = fileQueryScan
+				} else if poolCtx.Err() == nil {
+					failureOnce.Do(func() {
+						failure = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+queueing:
+	for i, fileQuery := range fileQueries {
+		if fileQuery.Status != caching.FileAdded && fileQuery.Status != caching.FileModified {
+			continue
+		}
+
+		select {
+		case queryIndexes <- i:
+		case <-poolCtx.Done():
+			break queueing
+		}
+	}
+
+	close(queryIndexes)
+	workers.Wait()
+
+	return fileQueryScans, failure
+}
+
+func (scanner *Scanner) workerCount() int {
+	if scanner.Workers < 1 {
+		return 1
+	}
+
+	return scanner.Workers
+}
+
+// scanFileQuery fetches and scans a single added or modified file, independently of the rest of the files
+func (scanner *Scanner) scanFileQuery(
+	ctx context.Context,
+	githubClient *github.Client,
+	fileQuery caching.GitHubFileQuery,
+	skippers *fileSkippers) (fileQueryScan, error) {
+
+	// Vendored, generated and minified files are listed rather than scanned
+	skipper := scanner.getFileSkipper(ctx, githubClient, fileQuery, skippers)
+	if reason := skipper.skipReasonForPath(fileQuery.FileName); reason != "" {
+		result := &FileScanResult{
+			Skipped: &SkippedFile{Path: fileQuery.FileName, PermalinkURL: fileQuery.BlobURL, Reason: reason},
+		}
+
+		if len(scanner.Honeytokens) > 0 {
+			file, err := caching.GetFile(ctx, fileQuery, githubClient)
+			if err != nil {
+				return fileQueryScan{}, err
+			}
+			result.Matches = scanner.checkSkippedFileForHoneytokens(file, splitContentLines(file.Content))
+		}
+
+		return fileQueryScan{result: result}, nil
+	}
+
+	log.Printf("Checking %s from %s", fileQuery.FileName, fileQuery.CommitSHA)
+
+	// In diff mode only the added lines are scanned, unless the patch is missing or truncated
+	if scanner.DiffMode && fileQuery.Patch != nil {
+		if patch, ok := parsePatch(*fileQuery.Patch); ok {
+			result, err := scanner.checkFilePatch(ctx, fileQuery, patch, skipper)
+			return fileQueryScan{result: result, patch: patch}, err
+		}
+		log.Printf("Patch for %s from %s is truncated, scanning the whole file", fileQuery.FileName, fileQuery.CommitSHA)
+	}
+
+	result, err := scanner.checkFileContentFromQuery(ctx, githubClient, fileQuery, skipper)
+	return fileQueryScan{result: result}, err
+}
+
+// cancelledScanResult marks an added or modified file which was left unscanned by a cancelled scan as incomplete
+func cancelledScanResult(fileQuery caching.GitHubFileQuery, err error) CommitScanResult {
+	log.Printf("Scan of %s from %s cancelled: %v", fileQuery.FileName, fileQuery.CommitSHA, err)
+
+	return CommitScanResult{
+		Commit: fileQuery.CommitSHA,
+		Incomplete: []IncompleteScan{{
+			Path:         fileQuery.FileName,
+			PermalinkURL: fileQuery.BlobURL,
+			Reasons:      []string{fmt.Sprintf("scan cancelled before the file was scanned: %v", err)},
+		}},
+	}
+}
+
 func (scanner *Scanner) CheckFileContentFromQuery(
+	ctx context.Context,
+	githubClient *github.Client,
+	fileQuery caching.GitHubFileQuery) (*FileScanResult, error) {
+	return scanner.checkFileContentFromQuery(ctx, githubClient, fileQuery, nil)
+}
+
+func (scanner *Scanner) checkFileContentFromQuery(
+	ctx context.Context,
 	githubClient *github.Client,
-	fileQuery caching.GitHubFileQuery) ([]FileContentMatch, error) {
+	fileQuery caching.GitHubFileQuery,
+	skipper *fileSkipper) (*FileScanResult, error) {
 
 	// Can't check the Content of a deleted file, just error our here and save ourselves another HTTP request
 	if fileQuery.Status == caching.FileRemoved {
@@ -121,109 +406,401 @@ func (scanner *Scanner) CheckFileContentFromQuery(
 		return nil, errors.New(errMessage)
 	}
 
-	file, err := caching.GetFile(fileQuery, githubClient)
+	file, err := caching.GetFile(ctx, fileQuery, githubClient)
 	if err != nil {
 		return nil, err
 	}
 
-	return scanner.CheckFileContent(file)
+	if reason := skipper.skipReasonForContent(file.Path, file.Content); reason != "" {
+		return &FileScanResult{
+			Matches: scanner.checkSkippedFileForHoneytokens(file, splitContentLines(file.Content)),
+			Skipped: &SkippedFile{Path: file.Path, PermalinkURL: file.PermalinkURL, Reason: reason},
+		}, nil
+	}
+
+	return scanner.CheckFileContent(ctx, file)
 }
 
-func (scanner *Scanner) CheckFileContent(file *caching.File) ([]FileContentMatch, error) {
+// checkFilePatch scans only the lines added to a file by a commit, without fetching the file's content
+func (scanner *Scanner) checkFilePatch(
+	ctx context.Context,
+	fileQuery caching.GitHubFileQuery,
+	patch *filePatch,
+	skipper *fileSkipper) (*FileScanResult, error) {
+
+	file := &caching.File{
+		CommitSHA:    fileQuery.CommitSHA,
+		Path:         fileQuery.FileName,
+		PermalinkURL: fileQuery.BlobURL,
+		Status:       fileQuery.Status,
+	}
 
-	var result []FileContentMatch
+	if reason := skipper.skipReasonForContent(file.Path, patch.addedContent()); reason != "" {
+		return &FileScanResult{
+			Matches: scanner.checkSkippedFileForHoneytokens(file, patch.added),
+			Skipped: &SkippedFile{Path: file.Path, PermalinkURL: file.PermalinkURL, Reason: reason},
+		}, nil
+	}
 
-	lineMatches, err := scanner.CheckContent(file.Content)
+	contentScanResult, err := scanner.scanLines(ctx, fileQuery.FileName, patch.added, false)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(lineMatches) > 0 {
-		for _, lineMatch := range lineMatches {
-			fileMatch := FileContentMatch{
-				File:      *file,
-				LineMatch: lineMatch,
-			}
+	return newFileScanResult(file, contentScanResult), nil
+}
+
+// fileSkippers holds the file skipper for each commit, which is shared by the workers scanning the commit's files
+type fileSkippers struct {
+	mutex    sync.Mutex
+	byCommit map[string]*fileSkipper
+}
+
+// getFileSkipper returns the file skipper for a query's commit, loading the commit's .gitattributes the first time.
+// Only the .gitattributes file in the root of the repository is read.
+func (scanner *Scanner) getFileSkipper(
+	ctx context.Context,
+	githubClient *github.Client,
+	fileQuery caching.GitHubFileQuery,
+	skippers *fileSkippers) *fileSkipper {
+
+	if scanner.ScanAllFiles {
+		return nil
+	}
+
+	skippers.mutex.Lock()
+	skipper, ok := skippers.byCommit[fileQuery.CommitSHA]
+	skippers.mutex.Unlock()
+	if ok {
+		return skipper
+	}
+
+	// Workers may load the same commit's .gitattributes at once, in which case the first one to finish is kept
+	skipper = &fileSkipper{}
+	attributesFile, err := caching.GetFile(ctx, caching.GitHubFileQuery{
+		RepoOwner: fileQuery.RepoOwner,
+		RepoName:  fileQuery.RepoName,
+		CommitSHA: fileQuery.CommitSHA,
+		FileName:  GitAttributesPath,
+		Status:    caching.FileModified,
+	}, githubClient)
+	if err == nil {
+		skipper.attributes = parseGitAttributes(attributesFile.Content)
+	}
+
+	skippers.mutex.Lock()
+	defer skippers.mutex.Unlock()
+	if loaded, ok := skippers.byCommit[fileQuery.CommitSHA]; ok {
+		return loaded
+	}
+	skippers.byCommit[fileQuery.CommitSHA] = skipper
+
+	return skipper
+}
+
+func (scanner *Scanner) CheckFileContent(ctx context.Context, file *caching.File) (*FileScanResult, error) {
+
+	contentScanResult, err := scanner.scanLines(ctx, file.Path, splitContentLines(file.Content), false)
+	if err != nil {
+		return nil, err
+	}
 
-			result = append(result, fileMatch)
+	return newFileScanResult(file, contentScanResult), nil
+}
+
+func newFileScanResult(file *caching.File, contentScanResult *ContentScanResult) *FileScanResult {
+
+	result := &FileScanResult{}
+
+	for _, lineMatch := range contentScanResult.Matches {
+		fileMatch := FileContentMatch{
+			File:      *file,
+			LineMatch: lineMatch,
 		}
+
+		result.Matches = append(result.Matches, fileMatch)
 	}
 
-	return result, nil
+	if contentScanResult.IsIncomplete() {
+		log.Printf("Scan of %s from %s is incomplete: %s", file.Path, file.CommitSHA, strings.Join(contentScanResult.IncompleteReasons, "; "))
+		result.Incomplete = &IncompleteScan{
+			Path:         file.Path,
+			PermalinkURL: file.PermalinkURL,
+			Reasons:      contentScanResult.IncompleteReasons,
+		}
+	}
+
+	return result
+}
+
+func (scanner *Scanner) CheckContent(ctx context.Context, content string) ([]LineMatch, error) {
+
+	result, err := scanner.scanLines(ctx, "", splitContentLines(content), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.IsIncomplete() {
+		log.Printf("Content scan is incomplete: %s", strings.Join(result.IncompleteReasons, "; "))
+	}
+
+	return result.Matches, nil
 }
 
-func (scanner *Scanner) CheckContent(content string) ([]LineMatch, error) {
+// ScanContent scans content line by line within the scanner's budget. If the budget runs out, the matches found so far
+// are returned along with the reasons the scan is incomplete. To scan content which can be cancelled, use CheckContent
+// or ScanReader.
+func (scanner *Scanner) ScanContent(content string) (*ContentScanResult, error) {
+	return scanner.ScanFileContent("", content)
+}
 
-	var result []LineMatch
+// ScanFileContent scans the content of a file, only using the patterns which apply to its path
+func (scanner *Scanner) ScanFileContent(path string, content string) (*ContentScanResult, error) {
 
 	// Todo: Multi-line scan first, then single-line scan around any multi-line match ranges
-	var lines = strings.Split(content, "\n")
-	for i, line := range lines {
-		lineNumber := i + 1
-		matchesOnLine, err := scanner.scanLineForPatterns(line)
-		if err != nil {
-			return nil, err
-		}
+	return scanner.scanLines(context.Background(), path, splitContentLines(content), false)
+}
 
-		// Todo: Another loop, any way around this?
-		if len(matchesOnLine) > 0 {
-			for _, matchOnLine := range matchesOnLine {
-				lineMatch := LineMatch{
-					LineNumber: lineNumber,
-					Match:      matchOnLine,
-				}
+// CheckProse checks Markdown written by people, such as the body of an issue or a comment, see ScanProse
+func (scanner *Scanner) CheckProse(ctx context.Context, content string) ([]LineMatch, error) {
 
-				result = append(result, lineMatch)
-			}
+	result, err := scanner.scanLines(ctx, "", splitContentLines(content), true)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.IsIncomplete() {
+		log.Printf("Prose scan is incomplete: %s", strings.Join(result.IncompleteReasons, "; "))
+	}
+
+	return result.Matches, nil
+}
+
+// ScanProse scans Markdown written by people. As well as the patterns, it looks for credentials disclosed in sentences,
+// and it ignores link targets unless the match is in a password or credential parameter within them.
+func (scanner *Scanner) ScanProse(content string) (*ContentScanResult, error) {
+	return scanner.scanLines(context.Background(), "", splitContentLines(content), true)
+}
+
+func splitContentLines(content string) []contentLine {
+	var lines []contentLine
+	for i, line := range strings.Split(content, "\n") {
+		lines = append(lines, contentLine{number: i + 1, text: line})
+	}
+
+	return lines
+}
+
+func (scanner *Scanner) scanLines(
+	ctx context.Context,
+	path string,
+	lines []contentLine,
+	prose bool) (*ContentScanResult, error) {
+
+	scan, err := scanner.newContentScan(ctx, path, prose)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ContentScanResult{}
+	for _, line := range lines {
+		if !scan.needsLines() {
+			break
 		}
+		result.Matches = append(result.Matches, scan.addLine(line)...)
 	}
 
+	matches, reasons := scan.finish()
+	result.Matches = append(result.Matches, matches...)
+	result.IncompleteReasons = reasons
+
 	return result, nil
 }
 
-func (scanner *Scanner) scanLineForPatterns(line string) ([]Match, error) {
-	var matches []Match
+// newLineMatch adds the masked previews to a match, so that it can be reported
+func (scanner *Scanner) newLineMatch(lineNumber int, match Match) LineMatch {
+	match.Preview = maskValue(match.value, scanner.Preview)
+	match.Length = utf8.RuneCountInString(match.value)
+	if match.context != "" {
+		match.ContextPreview = match.context[:match.contextOffset] +
+			match.Preview +
+			match.context[match.contextOffset+len(match.value):]
+	}
+
+	return LineMatch{
+		LineNumber: lineNumber,
+		Match:      match,
+	}
+}
+
+func (scanner *Scanner) compilePatterns(path string) ([]compiledPattern, error) {
+	var patterns []compiledPattern
 	for _, pattern := range scanner.Patterns {
-		currentPatternMatches, err := scanLineForPattern(line, pattern)
+		if !pattern.AppliesToPath(path) {
+			continue
+		}
+
+		regex, err := pattern.GetRegexp()
 		if err != nil {
 			return nil, err
 		}
 
+		patterns = append(patterns, compiledPattern{
+			SearchPattern: pattern,
+			regex:         regex,
+			secretGroup:   regex.SubexpIndex(SecretGroupName),
+			specificity:   patternSpecificity(regex.String()),
+		})
+	}
+
+	return patterns, nil
+}
+
+func scanLineForPatterns(line string, lineNumber int, patterns []compiledPattern, state *scanState) []Match {
+	var matches []Match
+	lowerCaseLine := strings.ToLower(line)
+	for i, pattern := range patterns {
+		if state.patternExhausted(i) || !pattern.HasKeyword(lowerCaseLine) {
+			continue
+		}
+
+		started := time.Now()
+		currentPatternMatches := scanLineForPattern(line, lineNumber, pattern, state)
+		state.recordPattern(i, pattern.SearchPattern, len(line), time.Since(started), lineNumber)
+
 		if len(currentPatternMatches) > 0 {
 			matches = append(matches, currentPatternMatches...)
 		}
 	}
 
-	return matches, nil
+	return matches
 }
 
-func scanLineForPattern(line string, pattern SearchPattern) ([]Match, error) {
+func scanLineForPattern(line string, lineNumber int, pattern compiledPattern, state *scanState) []Match {
 	var matches []Match
-	regex, err := pattern.GetRegexp()
-	if err != nil {
-		return nil, err
-	}
+	var matchEnds []int
+
+	// Long lines are scanned in overlapping windows, so a match in the overlap may be found twice, or cut off by the
+	// end of one window and found again in full from the next. Where found matches overlap, the one which extends
+	// furthest is kept.
+	for _, window := range lineWindows(line, state.budget.MaxLineLength, state.budget.WindowOverlap) {
+		if !state.checkFileDuration(lineNumber) {
+			break
+		}
 
-	var regexMatches = regex.FindAllStringIndex(line, -1)
-	for _, match := range regexMatches {
-		var startIndex = match[0]
-		var endIndex = match[1]
-		value := line[startIndex:endIndex]
+		var regexMatches = pattern.regex.FindAllStringSubmatchIndex(line[window.start:window.end], -1)
+		for _, match := range regexMatches {
+			indices := offsetIndices(match, window.start)
+			if indices[1] == window.end && window.end < len(line) {
+				indices = extendMatch(line, pattern, indices, state.budget.MaxLineLength)
+			}
 
-		// Ignore if the matched string is allowed to be excluded from checks
-		if pattern.CanIgnore(value) {
-			continue
+			if len(matchEnds) > 0 && indices[1] <= matchEnds[len(matchEnds)-1] {
+				continue
+			}
+
+			lineMatch, ok := newPatternMatch(line, pattern, indices)
+			if !ok {
+				continue
+			}
+
+			for len(matchEnds) > 0 && indices[0] < matchEnds[len(matchEnds)-1] {
+				matches = matches[:len(matches)-1]
+				matchEnds = matchEnds[:len(matchEnds)-1]
+			}
+			matches = append(matches, lineMatch)
+			matchEnds = append(matchEnds, indices[1])
 		}
+	}
 
-		matches = append(matches, Match{
-			StartIndex: startIndex,
-			EndIndex:   endIndex,
-			value:      value,
-			Kind:       pattern.Kind,
-		})
+	return matches
+}
+
+// newPatternMatch makes a match from the submatch indices of a pattern on a line, returning false if the matched value
+// should not be reported
+func newPatternMatch(line string, pattern compiledPattern, indices []int) (Match, bool) {
+	// Only report the secret capture group if the pattern has one and it took part in the match
+	var startIndex = indices[0]
+	var endIndex = indices[1]
+	var context string
+	if pattern.secretGroup > 0 && indices[2*pattern.secretGroup] >= 0 {
+		startIndex = indices[2*pattern.secretGroup]
+		endIndex = indices[2*pattern.secretGroup+1]
+		context = line[indices[0]:indices[1]]
+	}
+
+	value := line[startIndex:endIndex]
+
+	// Ignore if the matched string is allowed to be excluded from checks
+	if len(value) == 0 || pattern.CanIgnore(value) {
+		return Match{}, false
+	}
+
+	// Ignore values which look too regular to be a secret
+	if pattern.Entropy > 0 && shannonEntropy(value) < pattern.Entropy {
+		return Match{}, false
+	}
+
+	if !pattern.acceptsValue(value) {
+		return Match{}, false
+	}
+
+	startColumn := utf8.RuneCountInString(line[:startIndex])
+	return Match{
+		StartIndex:    startIndex,
+		EndIndex:      endIndex,
+		StartColumn:   startColumn,
+		EndColumn:     startColumn + utf8.RuneCountInString(value),
+		value:         value,
+		Kind:          pattern.Kind,
+		Severity:      pattern.GetSeverity(),
+		specificity:   pattern.specificity,
+		codeOnly:      pattern.Scope == PatternScopeCode,
+		context:       context,
+		contextOffset: startIndex - indices[0],
+	}, true
+}
+
+// extendMatch matches a pattern again from the start of a match which was cut off by the end of its window, over at
+// most maxLength bytes of the line, so that a match starting near the end of a window isn't truncated
+func extendMatch(line string, pattern compiledPattern, indices []int, maxLength int) []int {
+	end := len(line)
+	if maxLength > 0 && indices[0]+maxLength < end {
+		end = runeBoundary(line, indices[0]+maxLength)
+	}
+
+	extended := pattern.regex.FindStringSubmatchIndex(line[indices[0]:end])
+	if extended == nil || extended[0] != 0 || extended[1] <= indices[1]-indices[0] {
+		return indices
 	}
 
-	return matches, nil
+	return offsetIndices(extended, indices[0])
+}
+
+// offsetIndices moves submatch indices found in part of a line to be relative to the whole line
+func offsetIndices(indices []int, offset int) []int {
+	result := make([]int, len(indices))
+	for i, index := range indices {
+		if index < 0 {
+			result[i] = index
+		} else {
+			result[i] = index + offset
+		}
+	}
+
+	return result
+}
+
+// resolveMatches marks previous unresolved matches in a file as resolved. If canResolve is nil, all of the file's matches
+// are resolved.
+func resolveMatches(commitScanResults []CommitScanResult, path string, canResolve func(FileContentMatch) bool) {
+	for i, previousScanResult := range commitScanResults {
+		for j, previousFileMatch := range previousScanResult.Matches {
+			if previousFileMatch.Path == path && (canResolve == nil || canResolve(previousFileMatch)) {
+				commitScanResults[i].Matches[j].Resolved = true
+			}
+		}
+	}
 }
 
 func getMatches(commitScanResults []CommitScanResult) []FileContentMatch {
@@ -235,6 +812,18 @@ func getMatches(commitScanResults []CommitScanResult) []FileContentMatch {
 	return result
 }
 
+// unknownMatches drops the matches which an earlier commit already found and which haven't been resolved since
+func unknownMatches(commitScanResults []CommitScanResult, matches []FileContentMatch) []FileContentMatch {
+	var result []FileContentMatch
+	for _, fileContentMatch := range matches {
+		if !MatchIsKnown(getMatches(commitScanResults), fileContentMatch) {
+			result = append(result, fileContentMatch)
+		}
+	}
+
+	return result
+}
+
 func MatchIsKnown(knownFileContentMatches []FileContentMatch, newFileContentMatch FileContentMatch) bool {
 	for _, knownFileContentMatch := range knownFileContentMatches {
 		if knownFileContentMatch.Path == newFileContentMatch.Path {
@@ -247,3 +836,22 @@ func MatchIsKnown(knownFileContentMatches []FileContentMatch, newFileContentMatc
 
 	return false
 }
+
+// filterMatchesToCode drops matches of patterns with the code scope which are outside of a line's strings and assigned
+// values, and describes where the matches in code are
+func filterMatchesToCode(matches []Match, regions []codeRegion) []Match {
+	var result []Match
+	for _, match := range matches {
+		region := codeRegionAt(regions, match.StartIndex, match.EndIndex)
+		if region != nil {
+			match.CodeContext = region.describe()
+			match.codeRegion = region
+		} else if match.codeOnly {
+			continue
+		}
+
+		result = append(result, match)
+	}
+
+	return result
+}
@@ -0,0 +1,254 @@
+package scanning
+
+import (
+	"Orca/pkg/caching"
+	"context"
+	"fmt"
+	"github.com/google/go-github/v33/github"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPositions(t *testing.T) {
+	scanner := &Scanner{
+		Patterns: []SearchPattern{
+			{Pattern: "secret", Kind: "Test Secret"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		lineNumber  int
+		startIndex  int
+		endIndex    int
+		startColumn int
+		endColumn   int
+	}{
+		{"ascii", "a secret", 1, 2, 8, 2, 8},
+		{"two byte runes", "éé secret", 1, 5, 11, 3, 9},
+		{"four byte runes", "🔑🔑 secret", 1, 9, 15, 3, 9},
+		{"crlf", "line\r\n日本 secret\r\n", 2, 7, 13, 3, 9},
+		{"invalid utf-8", "\xff\xfe secret", 1, 3, 9, 3, 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := scanner.CheckContent(context.Background(), test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(matches) != 1 {
+				t.Fatalf("expected 1 match but got %d", len(matches))
+			}
+
+			match := matches[0]
+			if match.LineNumber != test.lineNumber ||
+				match.StartIndex != test.startIndex ||
+				match.EndIndex != test.endIndex ||
+				match.StartColumn != test.startColumn ||
+				match.EndColumn != test.endColumn {
+				t.Errorf(
+					"expected line %d, bytes %d-%d, columns %d-%d but got line %d, bytes %d-%d, columns %d-%d",
+					test.lineNumber, test.startIndex, test.endIndex, test.startColumn, test.endColumn,
+					match.LineNumber, match.StartIndex, match.EndIndex, match.StartColumn, match.EndColumn)
+			}
+		})
+	}
+}
+
+func TestSecretGroup(t *testing.T) {
+	scanner := &Scanner{
+		Patterns: []SearchPattern{
+			{Pattern: `api_key\s*=\s*"(?P<secret>[^"]+)"`, Kind: "API key"},
+		},
+		Preview: DefaultPreviewOptions(),
+	}
+
+	matches, err := scanner.CheckContent(context.Background(), `api_key  =  "0123456789abcdef"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match but got %d", len(matches))
+	}
+
+	match := matches[0]
+	if match.value != "0123456789abcdef" || match.StartIndex != 13 || match.EndIndex != 29 {
+		t.Errorf("expected only the secret group to be matched but got %q at %d-%d", match.value, match.StartIndex, match.EndIndex)
+	}
+
+	if expected := `api_key  =  "0123********cdef"`; match.ContextPreview != expected {
+		t.Errorf("expected context %q but got %q", expected, match.ContextPreview)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	scanner := &Scanner{
+		Patterns:  []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}},
+		Detectors: DetectorOptions{Disabled: true},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := scanner.scanLines(ctx, "", splitContentLines("tok_123456\ntok_654321"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"scan cancelled at line 1: context canceled"}; len(result.Matches) != 0 ||
+		!reflect.DeepEqual(result.IncompleteReasons, expected) {
+		t.Errorf("expected no matches and %q but got %+v", expected, result)
+	}
+
+	// The files left unscanned are reported as incomplete, without fetching them
+	commitScanResults, err := scanner.CheckFileContentFromQueries(ctx, nil, []caching.GitHubFileQuery{
+		{CommitSHA: "a1", FileName: "removed.txt", Status: caching.FileRemoved},
+		{CommitSHA: "a1", FileName: "added.txt", Status: caching.FileAdded, BlobURL: "https://github.com/blob/a1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []CommitScanResult{{Commit: "a1", Incomplete: []IncompleteScan{{
+		Path:         "added.txt",
+		PermalinkURL: "https://github.com/blob/a1",
+		Reasons:      []string{"scan cancelled before the file was scanned: context canceled"},
+	}}}}
+	if !reflect.DeepEqual(commitScanResults, expected) {
+		t.Errorf("expected %+v but got %+v", expected, commitScanResults)
+	}
+}
+
+func TestCheckFileContentFromQueriesOrdering(t *testing.T) {
+	patch := func(content string) *string {
+		return &content
+	}
+
+	fileQueries := []caching.GitHubFileQuery{
+		{CommitSHA: "c1", FileName: "a.txt", Status: caching.FileAdded, Patch: patch("@@ -0,0 +1 @@\n+tok_111111")},
+		{CommitSHA: "c2", FileName: "a.txt", Status: caching.FileModified, Patch: patch("@@ -1 +1 @@\n-tok_111111\n+ok")},
+		{CommitSHA: "c3", FileName: "b.txt", Status: caching.FileAdded, Patch: patch("@@ -0,0 +1 @@\n+tok_222222")},
+		{
+			CommitSHA: "c4",
+			FileName:  "b.txt",
+			Status:    caching.FileModified,
+			Patch:     patch("@@ -1 +1,2 @@\n tok_222222\n+tok_222222"),
+		},
+		{CommitSHA: "c5", FileName: "b.txt", Status: caching.FileRemoved},
+		{CommitSHA: "c6", FileName: "c.txt", Status: caching.FileAdded, Patch: patch("@@ -0,0 +1 @@\n+tok_333333")},
+	}
+
+	// Files without matches give the workers something to race over
+	for i := 0; i < 50; i++ {
+		fileQueries = append(fileQueries, caching.GitHubFileQuery{
+			CommitSHA: "c7",
+			FileName:  fmt.Sprintf("file%d.txt", i),
+			Status:    caching.FileAdded,
+			Patch:     patch("@@ -0,0 +1 @@\n+nothing to see"),
+		})
+	}
+
+	expected := []string{"c1 a.txt resolved", "c3 b.txt resolved", "c6 c.txt"}
+	for _, workers := range []int{1, 8} {
+		scanner := &Scanner{
+			Patterns:     []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}},
+			Detectors:    DetectorOptions{Disabled: true},
+			DiffMode:     true,
+			ScanAllFiles: true,
+			Workers:      workers,
+		}
+
+		commitScanResults, err := scanner.CheckFileContentFromQueries(context.Background(), nil, fileQueries)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var matches []string
+		for _, commitScanResult := range commitScanResults {
+			for _, match := range commitScanResult.Matches {
+				description := commitScanResult.Commit + " " + match.Path
+				if match.Resolved {
+					description += " resolved"
+				}
+				matches = append(matches, description)
+			}
+		}
+
+		if !reflect.DeepEqual(matches, expected) {
+			t.Errorf("expected %q with %d workers but got %q", expected, workers, matches)
+		}
+	}
+}
+
+func TestCheckFileContentFromQueriesSkippedHoneytokens(t *testing.T) {
+
+	// The GitHub API has no .gitattributes for either commit
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	generated := "@@ -0,0 +1,2 @@\n+// Code generated by gen. DO NOT EDIT.\n+key = tok_000000"
+	fileQueries := []caching.GitHubFileQuery{
+		{CommitSHA: "skipped-1", FileName: "gen.go", Status: caching.FileAdded, Patch: &generated},
+		{CommitSHA: "skipped-2", FileName: "gen.go", Status: caching.FileModified, Patch: &generated},
+	}
+
+	scanner := &Scanner{
+		Patterns:    []SearchPattern{{Pattern: `tok_\d+`, Kind: "Token"}},
+		Detectors:   DetectorOptions{Disabled: true},
+		Honeytokens: []Honeytoken{{Name: "canary", Fingerprint: HoneytokenFingerprint("tok_000000")}},
+		DiffMode:    true,
+	}
+
+	commitScanResults, err := scanner.CheckFileContentFromQueries(context.Background(), client, fileQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commitScanResults) != 2 {
+		t.Fatalf("expected both commits to list the skipped file but got %+v", commitScanResults)
+	}
+
+	// The second commit's honeytoken is the one already found by the first
+	if len(commitScanResults[0].Matches) != 1 || commitScanResults[0].Matches[0].Honeytoken != "canary" {
+		t.Errorf("expected the first commit to find the honeytoken but got %+v", commitScanResults[0].Matches)
+	}
+	if len(commitScanResults[1].Skipped) != 1 || len(commitScanResults[1].Matches) != 0 {
+		t.Errorf("expected the second commit to skip the file without matches but got %+v", commitScanResults[1])
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		options  PreviewOptions
+		expected string
+	}{
+		{"default options", "AKIAZQ3DR5CANARY0001", DefaultPreviewOptions(), "AKIA************0001"},
+		{"capped at a quarter of the value", "abcdefghij", DefaultPreviewOptions(), "ab******ij"},
+		{"too short to reveal anything", "abc", DefaultPreviewOptions(), "***"},
+		{"empty value", "", DefaultPreviewOptions(), ""},
+		{"nothing revealed", "abcdefghijklmnop", PreviewOptions{}, "****************"},
+		{"prefix only", "abcdefghijklmnop", PreviewOptions{VisiblePrefix: 2}, "ab**************"},
+		{"negative lengths", "abcdefghijklmnop", PreviewOptions{VisiblePrefix: -1, VisibleSuffix: -1}, "****************"},
+		{"mask character", "abcdefghij", PreviewOptions{VisiblePrefix: 1, MaskCharacter: '•'}, "a•••••••••"},
+		{"multibyte runes", "пароль-секрет-01", DefaultPreviewOptions(), "паро********т-01"},
+		{"multibyte runes capped", "密码密码密码密码", DefaultPreviewOptions(), "密码****密码"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := maskValue(test.value, test.options); actual != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
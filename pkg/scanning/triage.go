@@ -0,0 +1,229 @@
+package scanning
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// minTriageDecisions is how many decisions the triage model needs, including at least one of each verdict, before it
+// scores matches
+const minTriageDecisions = 10
+
+// TriageOptions turns the triage model off for a repository
+type TriageOptions struct {
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// TriageDecision is a developer's verdict on a match. Only features which don't reveal the matched value are kept, so
+// that decisions can be stored and shared safely.
+type TriageDecision struct {
+	Kind          string    `json:"kind"`
+	Features      []string  `json:"features"`
+	FalsePositive bool      `json:"falsePositive"`
+	RecordedAt    time.Time `json:"recordedAt"`
+}
+
+// NewTriageDecision records a verdict on a match found in a file
+func NewTriageDecision(filePath string, match Match, falsePositive bool) TriageDecision {
+	return TriageDecision{
+		Kind:          match.Kind,
+		Features:      triageFeatures(filePath, &match),
+		FalsePositive: falsePositive,
+		RecordedAt:    time.Now().UTC(),
+	}
+}
+
+// LoadTriageDecisions reads the decisions recorded in a file, which is empty until the first decision is recorded
+func LoadTriageDecisions(triageFile string) ([]TriageDecision, error) {
+	data, err := ioutil.ReadFile(triageFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var decisions []TriageDecision
+	if err := json.Unmarshal(data, &decisions); err != nil {
+		return nil, fmt.Errorf("invalid triage decisions in %s: %v", triageFile, err)
+	}
+
+	return decisions, nil
+}
+
+// RecordTriageDecision adds a decision to a file of decisions
+func RecordTriageDecision(triageFile string, decision TriageDecision) error {
+	decisions, err := LoadTriageDecisions(triageFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(append(decisions, decision), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(triageFile, data, 0644)
+}
+
+var (
+	triageWordRegex      = regexp.MustCompile(`[A-Za-z][a-z]*|[0-9]+`)
+	triageCamelCaseRegex = regexp.MustCompile(`[A-Z]+(?:[a-z]+)?|[a-z]+|[0-9]+`)
+)
+
+// identifierWords splits an identifier such as dbPassword, DB_PASSWORD or db-password into lower case words
+func identifierWords(identifier string) []string {
+	var words []string
+	for _, word := range triageCamelCaseRegex.FindAllString(identifier, -1) {
+		words = append(words, strings.ToLower(word))
+	}
+
+	return words
+}
+
+// triageFeatures describes a match without its value: what found it, where it is, the names around it and the shape
+// of the value
+func triageFeatures(filePath string, match *Match) []string {
+	features := []string{"kind=" + match.Kind}
+
+	extension := strings.TrimPrefix(strings.ToLower(path.Ext(filePath)), ".")
+	if extension == "" {
+		extension = "none"
+	}
+	features = append(features, "ext="+extension)
+
+	for _, word := range triageWordRegex.FindAllString(strings.TrimSuffix(filePath, path.Ext(filePath)), -1) {
+		features = append(features, "path="+strings.ToLower(word))
+	}
+
+	if match.codeRegion != nil {
+		if match.codeRegion.kind == stringLiteralRegion {
+			features = append(features, "code=string")
+		} else {
+			features = append(features, "code=assignment")
+		}
+		for _, word := range identifierWords(match.codeRegion.assignedTo) {
+			features = append(features, "name="+word)
+		}
+	}
+
+	for _, word := range identifierWords(match.Location) {
+		features = append(features, "name="+word)
+	}
+
+	// The value's shape is bucketed so that it can't be recovered
+	features = append(features, fmt.Sprintf("entropy=%d", int(shannonEntropy(match.value))))
+	features = append(features, fmt.Sprintf("length=%d", bitLength(len(match.value))))
+	if strings.IndexFunc(match.value, unicode.IsSpace) >= 0 {
+		features = append(features, "value=spaces")
+	}
+
+	return features
+}
+
+func bitLength(value int) int {
+	length := 0
+	for ; value > 0; value >>= 1 {
+		length++
+	}
+
+	return length
+}
+
+// TriageScore is the triage model's view of a match
+type TriageScore struct {
+
+	// Confidence is the probability that the match is a real secret rather than a false positive
+	Confidence float64
+
+	// Decisions is how many decisions the model was trained on
+	Decisions int
+}
+
+// Describe explains the score for reports
+func (score *TriageScore) Describe() string {
+	return fmt.Sprintf("%.0f%% likely to be a real secret (from %d past triage decisions)",
+		score.Confidence*100, score.Decisions)
+}
+
+// TriageModel is a naive Bayes classifier trained on triage decisions, which estimates how likely a new match is to be
+// a real secret from the decisions on similar matches
+type TriageModel struct {
+	decisions      int
+	classDecisions [2]int
+	featureCounts  [2]map[string]int
+	featureTotals  [2]int
+	vocabulary     map[string]bool
+}
+
+const (
+	truePositiveClass  = 0
+	falsePositiveClass = 1
+)
+
+// TrainTriageModel trains a model on the decisions, returning nil if there aren't enough decisions to train on
+func TrainTriageModel(decisions []TriageDecision) *TriageModel {
+	model := &TriageModel{
+		featureCounts: [2]map[string]int{{}, {}},
+		vocabulary:    map[string]bool{},
+	}
+
+	for _, decision := range decisions {
+		class := truePositiveClass
+		if decision.FalsePositive {
+			class = falsePositiveClass
+		}
+
+		model.decisions++
+		model.classDecisions[class]++
+		for _, feature := range decision.Features {
+			model.featureCounts[class][feature]++
+			model.featureTotals[class]++
+			model.vocabulary[feature] = true
+		}
+	}
+
+	if model.decisions < minTriageDecisions || model.classDecisions[truePositiveClass] == 0 ||
+		model.classDecisions[falsePositiveClass] == 0 {
+		return nil
+	}
+
+	return model
+}
+
+// score returns the probability that a match with the features is a real secret. Laplace smoothing stops features
+// which were never seen with a verdict from ruling it out.
+func (model *TriageModel) score(features []string) TriageScore {
+	var logLikelihoods [2]float64
+	for class := range logLikelihoods {
+		logLikelihoods[class] = math.Log(float64(model.classDecisions[class]) / float64(model.decisions))
+		denominator := float64(model.featureTotals[class] + len(model.vocabulary))
+		for _, feature := range features {
+			logLikelihoods[class] += math.Log(float64(model.featureCounts[class][feature]+1) / denominator)
+		}
+	}
+
+	// Normalise in log space, as the likelihoods can be too small to represent
+	difference := logLikelihoods[falsePositiveClass] - logLikelihoods[truePositiveClass]
+	return TriageScore{
+		Confidence: 1 / (1 + math.Exp(difference)),
+		Decisions:  model.decisions,
+	}
+}
+
+// applyTriageModel scores a match, unless the model is off. Honeytokens are always real, so are never scored.
+func (scanner *Scanner) applyTriageModel(filePath string, match *Match) {
+	if scanner.Triage == nil || match.Honeytoken != "" {
+		return
+	}
+
+	score := scanner.Triage.score(triageFeatures(filePath, match))
+	match.Triage = &score
+}
@@ -0,0 +1,68 @@
+package scanning
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTriageModel(t *testing.T) {
+	scanner := &Scanner{
+		Patterns:  []SearchPattern{{Pattern: `(?:password|secret)\s*=\s*"(\w+)"`, Kind: "Password"}},
+		Detectors: DetectorOptions{Disabled: true},
+	}
+
+	scan := func(path string, content string) Match {
+		result, err := scanner.ScanFileContent(path, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Matches) != 1 {
+			t.Fatalf("expected one match in %s but got %d", path, len(result.Matches))
+		}
+		return result.Matches[0].Match
+	}
+
+	// Developers dismiss passwords in tests, and confirm them in deployment scripts
+	var decisions []TriageDecision
+	for i := 0; i < 6; i++ {
+		decisions = append(decisions,
+			NewTriageDecision("src/tests/loginTests.py", scan("src/tests/loginTests.py", `password = "pa55word"`), true),
+			NewTriageDecision("deploy/release.py", scan("deploy/release.py", `secret = "Xk29fLq0Zr8Tw"`), false))
+	}
+
+	for _, decision := range decisions {
+		for _, feature := range decision.Features {
+			if strings.Contains(feature, "pa55word") || strings.Contains(feature, "Xk29fLq0Zr8Tw") {
+				t.Fatalf("expected the features not to include the matched value but got %s", feature)
+			}
+		}
+	}
+
+	if TrainTriageModel(decisions[:minTriageDecisions-1]) != nil {
+		t.Error("expected no model from too few decisions")
+	}
+
+	scanner.Triage = TrainTriageModel(decisions)
+	if scanner.Triage == nil {
+		t.Fatal("expected a model")
+	}
+
+	testMatch := scan("src/tests/signupTests.py", `password = "letmein"`)
+	deployMatch := scan("deploy/rollback.py", `secret = "Qv83nWp2Lx7Ke"`)
+	if testMatch.Triage == nil || deployMatch.Triage == nil {
+		t.Fatal("expected the matches to be scored")
+	}
+	if testMatch.Triage.Confidence >= 0.5 || deployMatch.Triage.Confidence <= 0.5 {
+		t.Errorf("expected the test password to be scored lower than the deployment secret but got %v and %v",
+			testMatch.Triage.Confidence, deployMatch.Triage.Confidence)
+	}
+	if testMatch.Triage.Decisions != len(decisions) {
+		t.Errorf("expected %d decisions but got %d", len(decisions), testMatch.Triage.Decisions)
+	}
+
+	// Repositories can turn the model off
+	scanner.ApplyRepositoryConfig(&RepositoryConfig{Triage: &TriageOptions{Disabled: true}})
+	if match := scan("deploy/rollback.py", `secret = "Qv83nWp2Lx7Ke"`); match.Triage != nil {
+		t.Errorf("expected no score with the model turned off but got %+v", match.Triage)
+	}
+}
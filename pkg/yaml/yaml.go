@@ -0,0 +1,258 @@
+// Package yaml reads YAML documents with gopkg.in/yaml.v3 into a simpler tree of nodes for the detectors. Aliases
+// are replaced by the nodes they refer to, merge keys are applied and tags are ignored. Every node records where it is,
+// so that values can be reported against the line they came from.
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+type Kind int
+
+const (
+	ScalarNode Kind = iota
+	MappingNode
+	SequenceNode
+)
+
+type Node struct {
+	Kind Kind
+
+	// Value is a scalar's value, with any quotes removed and escapes replaced
+	Value string
+
+	// Line is the line the node starts on, counting from 1. For scalars, Start and End are the byte offsets of the
+	// value within that line, excluding quotes. A scalar which continues onto further lines only spans the part of it
+	// on its first line, so End-Start is shorter than its value.
+	Line  int
+	Start int
+	End   int
+
+	// Keys and Values hold a mapping's entries in the order they appear, and Items holds a sequence's entries
+	Keys   []string
+	Values []*Node
+	Items  []*Node
+}
+
+// Get returns the value of a key in a mapping, or nil if the node isn't a mapping or doesn't have the key
+func (node *Node) Get(key string) *Node {
+	if node == nil || node.Kind != MappingNode {
+		return nil
+	}
+
+	for i, existing := range node.Keys {
+		if existing == key {
+			return node.Values[i]
+		}
+	}
+
+	return nil
+}
+
+// GetValue returns the scalar value of a key in a mapping, or an empty string if there isn't one
+func (node *Node) GetValue(key string) string {
+	value := node.Get(key)
+	if value == nil || value.Kind != ScalarNode {
+		return ""
+	}
+
+	return value.Value
+}
+
+// Parse parses each of the documents in data
+func Parse(data []byte) ([]*Node, error) {
+	lines := strings.Split(string(data), "\n")
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var documents []*Node
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			return documents, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(document.Content) == 0 {
+			continue
+		}
+
+		converter := &converter{lines: lines, converted: map[*yaml.Node]*Node{}}
+		node, err := converter.convert(document.Content[0])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, node)
+	}
+}
+
+// converter turns a document's yaml.v3 nodes into Nodes. Anchored nodes are only converted once, and every alias of
+// them shares the result.
+type converter struct {
+	lines     []string
+	converted map[*yaml.Node]*Node
+}
+
+func (converter *converter) convert(node *yaml.Node) (*Node, error) {
+	if node.Kind == yaml.AliasNode {
+		return converter.convert(node.Alias)
+	}
+
+	if converted, ok := converter.converted[node]; ok {
+		return converted, nil
+	}
+
+	var result *Node
+	var err error
+	switch node.Kind {
+	case yaml.MappingNode:
+		result, err = converter.convertMapping(node)
+	case yaml.SequenceNode:
+		result = &Node{Kind: SequenceNode, Line: node.Line}
+		for _, item := range node.Content {
+			converted, err := converter.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, converted)
+		}
+	case yaml.ScalarNode:
+		result = &Node{Kind: ScalarNode, Value: node.Value}
+		result.Line, result.Start, result.End = converter.scalarPosition(node)
+	default:
+		err = fmt.Errorf("yaml: line %d: unexpected node", node.Line)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	converter.converted[node] = result
+	return result, nil
+}
+
+// convertMapping converts a mapping, adding the entries of any mappings merged into it with << which it doesn't set
+// itself. Earlier mappings in a merged sequence take precedence over later ones.
+func (converter *converter) convertMapping(node *yaml.Node) (*Node, error) {
+	result := &Node{Kind: MappingNode, Line: node.Line}
+	var merges []*Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("yaml: line %d: complex keys are not supported", key.Line)
+		}
+
+		value, err := converter.convert(node.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Tag == "!!merge" {
+			merges = append(merges, value)
+			continue
+		}
+
+		if result.Get(key.Value) != nil {
+			return nil, fmt.Errorf("yaml: line %d: duplicate key %s", key.Line, key.Value)
+		}
+		result.Keys = append(result.Keys, key.Value)
+		result.Values = append(result.Values, value)
+	}
+
+	for _, merge := range merges {
+		sources := []*Node{merge}
+		if merge.Kind == SequenceNode {
+			sources = merge.Items
+		}
+
+		for _, source := range sources {
+			if source.Kind != MappingNode {
+				return nil, fmt.Errorf("yaml: line %d: only mappings can be merged", merge.Line)
+			}
+
+			for i, key := range source.Keys {
+				if result.Get(key) == nil {
+					result.Keys = append(result.Keys, key)
+					result.Values = append(result.Values, source.Values[i])
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// scalarPosition finds the line a scalar's value starts on and the byte offsets of the value within it. yaml.v3 gives
+// the column of the scalar's first character, which is its opening quote if it has one, or the header of a block
+// scalar, whose value starts on the next line.
+func (converter *converter) scalarPosition(node *yaml.Node) (int, int, int) {
+	line := node.Line
+	text := converter.line(line)
+	start := byteOffset(text, node.Column)
+	if node.Value == "" {
+		return line, start, start
+	}
+
+	switch node.Style {
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		line++
+		for line < len(converter.lines) && strings.TrimSpace(converter.line(line)) == "" {
+			line++
+		}
+
+		text = converter.line(line)
+		return line, len(text) - len(strings.TrimLeft(text, " ")), len(text)
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		start++
+		return line, start, closingQuote(text, start, text[start-1])
+	}
+
+	// A plain scalar which continues onto the following lines only spans the part of it on its first line
+	if strings.HasPrefix(text[start:], node.Value) {
+		return line, start, start + len(node.Value)
+	}
+
+	return line, start, len(strings.TrimRight(text, " \t"))
+}
+
+func (converter *converter) line(number int) string {
+	if number < 1 || number > len(converter.lines) {
+		return ""
+	}
+
+	return strings.TrimSuffix(converter.lines[number-1], "\r")
+}
+
+// byteOffset converts a 1-based column, counted in characters, to a byte offset within a line
+func byteOffset(text string, column int) int {
+	characters := 1
+	for offset := range text {
+		if characters == column {
+			return offset
+		}
+		characters++
+	}
+
+	return len(text)
+}
+
+// closingQuote finds the closing quote of a quoted value on its first line, or the end of the line if it continues
+// onto the next. In double quoted values a quote can be escaped with a backslash, and in single quoted values by
+// doubling it.
+func closingQuote(text string, from int, quote byte) int {
+	for i := from; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+
+	return len(strings.TrimRight(text, " \t"))
+}
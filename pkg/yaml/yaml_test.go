@@ -0,0 +1,240 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	documents, err := Parse([]byte(`# A Kubernetes secret and a compose file
+apiVersion: v1
+kind: Secret
+metadata:
+  name: "db-creds"  # quoted
+data:
+  password: cGFzc3dvcmQ=
+  note: 'it''s # not a comment'
+---
+services:
+  db:
+    image: postgres
+    environment:
+    - POSTGRES_USER=orca
+    - POSTGRES_PASSWORD=hunter2
+  app:
+    command: >-
+      run
+      --fast
+    ports: [80, 443]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents but got %d", len(documents))
+	}
+
+	secret := documents[0]
+	if secret.GetValue("kind") != "Secret" || secret.Get("metadata").GetValue("name") != "db-creds" {
+		t.Errorf("unexpected secret %+v", secret)
+	}
+
+	password := secret.Get("data").Get("password")
+	if password.Value != "cGFzc3dvcmQ=" || password.Line != 7 || password.Start != 12 || password.End != 24 {
+		t.Errorf("expected password on line 7 at 12-24 but got %+v", password)
+	}
+
+	if note := secret.Get("data").GetValue("note"); note != "it's # not a comment" {
+		t.Errorf("unexpected note \"%s\"", note)
+	}
+
+	services := documents[1].Get("services")
+	environment := services.Get("db").Get("environment")
+	if environment == nil || environment.Kind != SequenceNode || len(environment.Items) != 2 {
+		t.Fatalf("expected environment to be a sequence of 2 items but got %+v", environment)
+	}
+
+	item := environment.Items[1]
+	if item.Value != "POSTGRES_PASSWORD=hunter2" || item.Line != 15 || item.Start != 6 {
+		t.Errorf("unexpected environment item %+v", item)
+	}
+
+	app := services.Get("app")
+	if command := app.GetValue("command"); command != "run --fast" {
+		t.Errorf("unexpected command \"%s\"", command)
+	}
+
+	ports := app.Get("ports")
+	if ports == nil || ports.Kind != SequenceNode || len(ports.Items) != 2 || ports.Items[1].Value != "443" {
+		t.Errorf("expected ports to be a sequence of 80 and 443 but got %+v", ports)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected interface{}
+	}{
+		{"plain", "value: hello world", "hello world"},
+		{"multi-line plain", "value: first\n  second\n\n  third\nnext: x", "first second\nthird"},
+		{"multi-line double quoted", "value: \"first\n  second\\\n  third\"", "first secondthird"},
+		{"multi-line single quoted", "value: 'it''s\n\n  # not a comment'", "it's\n# not a comment"},
+		{"escapes", `value: "tab\there\n\x41\u00e9\U0001F600\"\\"`, "tab\there\nA\u00e9\U0001F600\"\\"},
+		{"hash in plain", "value: a#b # comment", "a#b"},
+		{"literal", "value: |\n  line one\n    indented\n\n  line two\n\nnext: x", "line one\n  indented\n\nline two\n"},
+		{"literal strip", "value: |-\n  text\n\n", "text"},
+		{"literal keep", "value: |+\n  text\n\n", "text\n\n"},
+		{"literal with indentation", "value: |2\n    indented\n  text\n", "  indented\ntext\n"},
+		{"folded", "value: >\n  one\n  two\n\n  three\n    more\n  four\n", "one two\nthree\n  more\nfour\n"},
+		{"empty block", "value: |\nnext: x", ""},
+		{"flow sequence", "value: [a, 'b, c', \"d\", [e]]", []interface{}{"a", "b, c", "d", []interface{}{"e"}}},
+		{
+			"flow mapping",
+			"value: {a: 1, 'b': [x, y], c: {d: e}, f}",
+			map[string]interface{}{
+				"a": "1",
+				"b": []interface{}{"x", "y"},
+				"c": map[string]interface{}{"d": "e"},
+				"f": "",
+			},
+		},
+		{
+			"multi-line flow",
+			"value: [\n  a, # first\n  {b: http://host:80},\n  c: d,\n]",
+			[]interface{}{"a", map[string]interface{}{"b": "http://host:80"}, map[string]interface{}{"c": "d"}},
+		},
+		{"empty flow", "value: []", []interface{}{}},
+		{"alias", "base: &base secret\nvalue: *base", "secret"},
+		{"tag", "value: !!str 123", "123"},
+		{
+			"merge key",
+			"base: &base {a: 1, b: 2}\nvalue:\n  <<: *base\n  b: 3",
+			map[string]interface{}{"a": "1", "b": "3"},
+		},
+		{
+			"merge sequence",
+			"x: &x {a: 1}\ny: &y {a: 2, b: 2}\nvalue:\n  <<: [*x, *y]",
+			map[string]interface{}{"a": "1", "b": "2"},
+		},
+		{
+			"anchored mapping",
+			"base: &base\n  user: orca\nvalue: *base",
+			map[string]interface{}{"user": "orca"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			documents, err := Parse([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(documents) != 1 {
+				t.Fatalf("expected 1 document but got %d", len(documents))
+			}
+
+			actual := toValue(documents[0].Get("value"))
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %#v but got %#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+// toValue converts a node to strings, slices and maps so it can be compared
+func toValue(node *Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Kind {
+	case SequenceNode:
+		items := []interface{}{}
+		for _, item := range node.Items {
+			items = append(items, toValue(item))
+		}
+		return items
+	case MappingNode:
+		entries := map[string]interface{}{}
+		for i, key := range node.Keys {
+			entries[key] = toValue(node.Values[i])
+		}
+		return entries
+	default:
+		return node.Value
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	documents, err := Parse([]byte("key: |\n  secret\nlist: [a, \"bc\"]\nmulti: first\n  second\nclé: 'é #1'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		node  *Node
+		line  int
+		start int
+		end   int
+	}{
+		{"block scalar", documents[0].Get("key"), 2, 2, 8},
+		{"flow item", documents[0].Get("list").Items[0], 3, 7, 8},
+		{"quoted flow item", documents[0].Get("list").Items[1], 3, 11, 13},
+		{"multi-line plain", documents[0].Get("multi"), 4, 7, 12},
+		{"after multibyte characters", documents[0].Get("clé"), 6, 7, 12},
+	}
+
+	for _, test := range tests {
+		node := test.node
+		if node.Line != test.line || node.Start != test.start || node.End != test.end {
+			t.Errorf("expected %s on line %d at %d-%d but got %+v", test.name, test.line, test.start, test.end, node)
+		}
+	}
+}
+
+func TestParseDocuments(t *testing.T) {
+	documents, err := Parse([]byte("%YAML 1.1\n---\na: &x 1\n...\n---\nb: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(documents) != 2 || documents[0].GetValue("a") != "1" || documents[1].GetValue("b") != "2" {
+		t.Errorf("unexpected documents %+v", documents)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"- item\nkey: value\n",
+		"key: value\nnot a key\n",
+		"key: value\n  other: value\n",
+		"key: 1\nkey: 2\n",
+		"key: \"unterminated\n",
+		"key: 'unterminated\n",
+		"key: \"closed\" extra\n",
+		"key: \"\\q\"\n",
+		"key: \"\\u12\"\n",
+		"key: [a, b\n",
+		"key: [a, , b]\n",
+		"key: {a: 1\n",
+		"key: {a: 1, a: 2}\n",
+		"key: [a] extra\n",
+		"key: *missing\n",
+		"key: |x\n  text\n",
+		"key: |++\n  text\n",
+		"? [complex]\n: key\n",
+		"key: {[a]: b}\n",
+		"\tkey: value\n",
+		"key: &\n",
+		"--- key: value\n",
+		"<<: value\n",
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("expected an error parsing %q", data)
+		}
+	}
+}